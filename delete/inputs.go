@@ -1,28 +1,43 @@
 package main
 
 import (
+	"time"
+
 	"github.com/digitalocean/app_action/utils"
-	gha "github.com/sethvargo/go-githubactions"
 )
 
 // inputs are the inputs for the action.
 type inputs struct {
-	token          string
-	appName        string
-	appID          string
-	fromPRPreview  bool
-	ignoreNotFound bool
+	token                string
+	appName              string
+	appID                string
+	fromPRPreview        bool
+	previewNameTemplate  string
+	deleteLegacyPreviews bool
+	ignoreNotFound       bool
+	apiMaxRetries        int
+	apiMaxBackoff        time.Duration
+	apiBaseURL           string
+	proxyURL             string
+	caBundle             string
 }
 
 // getInputs gets the inputs for the action.
-func getInputs(a *gha.Action) (inputs, error) {
+func getInputs(a utils.Runtime) (inputs, error) {
 	var in inputs
 	for _, err := range []error{
 		utils.InputAsString(a, "token", true, &in.token),
 		utils.InputAsString(a, "app_name", false, &in.appName),
 		utils.InputAsString(a, "app_id", false, &in.appID),
 		utils.InputAsBool(a, "from_pr_preview", false, &in.fromPRPreview),
+		utils.InputAsString(a, "preview_name_template", false, &in.previewNameTemplate),
+		utils.InputAsBool(a, "delete_legacy_previews", false, &in.deleteLegacyPreviews),
 		utils.InputAsBool(a, "ignore_not_found", false, &in.ignoreNotFound),
+		utils.InputAsInt(a, "api_max_retries", false, &in.apiMaxRetries),
+		utils.InputAsDuration(a, "api_max_backoff", false, &in.apiMaxBackoff),
+		utils.InputAsString(a, "api_base_url", false, &in.apiBaseURL),
+		utils.InputAsString(a, "proxy_url", false, &in.proxyURL),
+		utils.InputAsString(a, "ca_bundle", false, &in.caBundle),
 	} {
 		if err != nil {
 			return in, err
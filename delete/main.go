@@ -2,16 +2,28 @@ package main
 
 import (
 	"context"
+	_ "embed"
+	"fmt"
 	"net/http"
+	"os"
 
 	"github.com/digitalocean/app_action/utils"
 	"github.com/digitalocean/godo"
-	gha "github.com/sethvargo/go-githubactions"
 )
 
+// actionYAML is the action's metadata. It defines the inputs when running as a CLI.
+//
+//go:embed action.yml
+var actionYAML []byte
+
 func main() {
 	ctx := context.Background()
-	a := gha.New()
+	a, finish, err := utils.NewRuntime(actionYAML)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	defer finish()
 
 	in, err := getInputs(a)
 	if err != nil {
@@ -24,41 +36,67 @@ func main() {
 		a.Fatalf("either app_id, app_name, or from_pr_preview must be set")
 	}
 
-	ghCtx, err := a.Context()
+	clientConfig := utils.ClientConfig{
+		BaseURL:  in.apiBaseURL,
+		ProxyURL: in.proxyURL,
+		CABundle: in.caBundle,
+	}
+	do, err := utils.NewClient(in.token, "do-app-action-delete", clientConfig)
 	if err != nil {
-		a.Fatalf("failed to get GitHub context: %v", err)
+		a.Fatalf("failed to create API client: %v", err)
 	}
+	apps := utils.NewRetryingAppsService(do.Apps, utils.RetryConfig{
+		MaxRetries: in.apiMaxRetries,
+		MaxBackoff: in.apiMaxBackoff,
+	})
 
-	do := godo.NewFromToken(in.token)
-	do.UserAgent = "do-app-action-delete"
-
-	appID := in.appID
-	if appID == "" {
+	appIDs := []string{in.appID}
+	if in.appID == "" {
 		appName := in.appName
+		var found []*godo.App
 		if appName == "" {
-			repoOwner, repo := ghCtx.Repo()
-			appName = utils.GenerateAppName(repoOwner, repo, ghCtx.HeadRef)
+			ciCtx, err := utils.NewCIContext(a, os.Getenv)
+			if err != nil {
+				a.Fatalf("failed to get CI context: %v", err)
+			}
+			appName, err = utils.GenerateAppName(in.previewNameTemplate, ciCtx)
+			if err != nil {
+				a.Fatalf("failed to generate app name: %v", err)
+			}
+			found, err = findPreviewApps(ctx, a, apps, appName, ciCtx, in.deleteLegacyPreviews)
+			if err != nil {
+				a.Fatalf("failed to find app: %v", err)
+			}
+		} else {
+			app, err := utils.FindAppByName(ctx, apps, appName)
+			if err != nil {
+				a.Fatalf("failed to find app: %v", err)
+			}
+			if app != nil {
+				found = append(found, app)
+			}
 		}
 
-		app, err := utils.FindAppByName(ctx, do.Apps, appName)
-		if err != nil {
-			a.Fatalf("failed to find app: %v", err)
-		}
-		if app == nil {
+		if len(found) == 0 {
 			if in.ignoreNotFound {
 				a.Infof("app %q not found, ignoring", appName)
 				return
 			}
 			a.Fatalf("app %q not found", appName)
 		}
-		appID = app.ID
+		appIDs = nil
+		for _, app := range found {
+			appIDs = append(appIDs, app.ID)
+		}
 	}
 
-	if resp, err := do.Apps.Delete(ctx, appID); err != nil {
-		if resp.StatusCode == http.StatusNotFound && in.ignoreNotFound {
-			a.Infof("app %q not found, ignoring", appID)
-			return
+	for _, appID := range appIDs {
+		if resp, err := apps.Delete(ctx, appID); err != nil {
+			if resp != nil && resp.StatusCode == http.StatusNotFound && in.ignoreNotFound {
+				a.Infof("app %q not found, ignoring", appID)
+				continue
+			}
+			a.Fatalf("failed to delete app: %v", err)
 		}
-		a.Fatalf("failed to delete app: %v", err)
 	}
 }
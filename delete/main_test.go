@@ -0,0 +1,178 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"os/exec"
+	"testing"
+
+	"github.com/digitalocean/app_action/internal/fakeapi"
+	"github.com/digitalocean/app_action/utils"
+	"github.com/digitalocean/godo"
+	"github.com/stretchr/testify/require"
+)
+
+// runMainEnv makes the test binary run the action instead of the tests.
+const runMainEnv = "APP_ACTION_TEST_RUN_MAIN"
+
+func TestMain(m *testing.M) {
+	if os.Getenv(runMainEnv) == "true" {
+		main()
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// runAction runs the action as a CLI against the given fake API and returns
+// its logs and its exit code.
+func runAction(t *testing.T, srv *fakeapi.Server, env []string, args ...string) (string, int) {
+	t.Helper()
+
+	var stderr bytes.Buffer
+	cmd := exec.Command(os.Args[0], args...)
+	cmd.Env = append([]string{
+		runMainEnv + "=true",
+		"GITHUB_ACTIONS=false",
+		"APP_ACTION_TOKEN=token",
+		"APP_ACTION_API_BASE_URL=" + srv.URL,
+	}, env...)
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		require.ErrorAs(t, err, &exitErr, "stderr: %s", stderr.String())
+		return stderr.String(), exitErr.ExitCode()
+	}
+	return stderr.String(), 0
+}
+
+// createApp creates an app with the given name on the fake API. If a repo is
+// given, the app is built from its branch. Otherwise it's deployed from an image.
+func createApp(t *testing.T, srv *fakeapi.Server, name, repo, branch string) {
+	t.Helper()
+
+	service := &godo.AppServiceSpec{
+		Name:  "web",
+		Image: &godo.ImageSourceSpec{RegistryType: godo.ImageSourceSpecRegistryType_DOCR, Repository: "web", Tag: "v1"},
+	}
+	if repo != "" {
+		service.Image = nil
+		service.GitHub = &godo.GitHubSourceSpec{Repo: repo, Branch: branch}
+	}
+	createAppFromSpec(t, srv, &godo.AppSpec{
+		Name:     name,
+		Services: []*godo.AppServiceSpec{service},
+	})
+}
+
+// createAppFromSpec creates an app with the given spec on the fake API.
+func createAppFromSpec(t *testing.T, srv *fakeapi.Server, spec *godo.AppSpec) {
+	t.Helper()
+
+	client, err := utils.NewClient("token", "test", utils.ClientConfig{BaseURL: srv.URL})
+	require.NoError(t, err)
+	_, _, err = client.Apps.Create(context.Background(), &godo.AppCreateRequest{Spec: spec})
+	require.NoError(t, err)
+}
+
+func TestEndToEnd(t *testing.T) {
+	tests := []struct {
+		name         string
+		env          []string
+		args         []string
+		extraApps    []*godo.AppSpec
+		expectedCode int
+		expectedApps []string
+	}{{
+		name:         "by name",
+		args:         []string{"--app-name", "foo"},
+		expectedApps: []string{"bar", "pr-3-repo-owner", "feature-branch"},
+	}, {
+		name:         "by ID",
+		args:         []string{"--app-id", "app-2"},
+		expectedApps: []string{"foo", "pr-3-repo-owner", "feature-branch"},
+	}, {
+		name: "from PR preview",
+		env:  []string{"APP_ACTION_CI_REPOSITORY=owner/repo", "APP_ACTION_CI_BRANCH=feature/branch", "APP_ACTION_CI_PR_NUMBER=3"},
+		args: []string{"--from-pr-preview"},
+		// The app named after the legacy naming scheme is only deleted on request.
+		expectedApps: []string{"foo", "bar", "feature-branch"},
+	}, {
+		name:         "from PR preview with legacy previews",
+		env:          []string{"APP_ACTION_CI_REPOSITORY=owner/repo", "APP_ACTION_CI_BRANCH=feature/branch", "APP_ACTION_CI_PR_NUMBER=3"},
+		args:         []string{"--from-pr-preview", "--delete-legacy-previews"},
+		expectedApps: []string{"foo", "bar"},
+	}, {
+		name:         "from PR preview with legacy name only",
+		env:          []string{"APP_ACTION_CI_REPOSITORY=owner/repo", "APP_ACTION_CI_BRANCH=feature/branch", "APP_ACTION_CI_PR_NUMBER=4"},
+		args:         []string{"--from-pr-preview", "--delete-legacy-previews"},
+		expectedApps: []string{"foo", "bar", "pr-3-repo-owner"},
+	}, {
+		name: "from PR preview with a long-lived app named like a legacy preview",
+		env:  []string{"APP_ACTION_CI_REPOSITORY=owner/repo", "APP_ACTION_CI_BRANCH=staging", "APP_ACTION_CI_PR_NUMBER=5"},
+		args: []string{"--from-pr-preview", "--delete-legacy-previews", "--ignore-not-found"},
+		extraApps: []*godo.AppSpec{{
+			Name: "staging",
+			Services: []*godo.AppServiceSpec{{
+				Name:   "web",
+				GitHub: &godo.GitHubSourceSpec{Repo: "owner/repo", Branch: "staging", DeployOnPush: true},
+			}},
+			Domains: []*godo.AppDomainSpec{{Domain: "staging.example.com"}},
+		}},
+		// The app is built from the branch but isn't a preview, so it survives.
+		expectedApps: []string{"foo", "bar", "pr-3-repo-owner", "feature-branch", "staging"},
+	}, {
+		name:         "from PR preview of another repository",
+		env:          []string{"APP_ACTION_CI_REPOSITORY=other/repo", "APP_ACTION_CI_BRANCH=feature/branch", "APP_ACTION_CI_PR_NUMBER=3"},
+		args:         []string{"--from-pr-preview"},
+		expectedCode: 1,
+		expectedApps: []string{"foo", "bar", "pr-3-repo-owner", "feature-branch"},
+	}, {
+		name:         "not found",
+		args:         []string{"--app-name", "baz"},
+		expectedCode: 1,
+		expectedApps: []string{"foo", "bar", "pr-3-repo-owner", "feature-branch"},
+	}, {
+		name:         "not found ignored",
+		args:         []string{"--app-name", "baz", "--ignore-not-found"},
+		expectedApps: []string{"foo", "bar", "pr-3-repo-owner", "feature-branch"},
+	}, {
+		name:         "ID not found ignored",
+		args:         []string{"--app-id", "app-42", "--ignore-not-found"},
+		expectedApps: []string{"foo", "bar", "pr-3-repo-owner", "feature-branch"},
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			srv := fakeapi.New()
+			defer srv.Close()
+			createApp(t, srv, "foo", "", "")
+			createApp(t, srv, "bar", "", "")
+			createApp(t, srv, "pr-3-repo-owner", "owner/repo", "feature/branch")
+			// Named after the legacy naming scheme of preview apps.
+			createApp(t, srv, "feature-branch", "owner/repo", "feature/branch")
+			for _, spec := range test.extraApps {
+				createAppFromSpec(t, srv, spec)
+			}
+
+			logs, code := runAction(t, srv, test.env, test.args...)
+			require.Equal(t, test.expectedCode, code, logs)
+
+			var apps []string
+			for _, app := range srv.Apps() {
+				apps = append(apps, app.GetSpec().GetName())
+			}
+			require.Equal(t, test.expectedApps, apps)
+		})
+	}
+}
+
+func TestEndToEndUnreachableAPI(t *testing.T) {
+	srv := fakeapi.New()
+	srv.Close()
+
+	logs, code := runAction(t, srv, nil, "--app-id", "app-1", "--ignore-not-found", "--api-max-retries", "0")
+	require.Equal(t, 1, code, logs)
+	require.Contains(t, logs, "failed to delete app")
+}
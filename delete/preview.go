@@ -0,0 +1,41 @@
+package main
+
+import (
+	"context"
+
+	"github.com/digitalocean/app_action/utils"
+	"github.com/digitalocean/godo"
+)
+
+// findPreviewApps returns the preview apps with the given name and, if legacy
+// is set and it exists, the one named after the legacy naming scheme. As legacy
+// names are only derived from the branch, such an app is only returned if it
+// looks like a preview of the branch of the repository in the given context.
+func findPreviewApps(ctx context.Context, a utils.Runtime, apps godo.AppsService, appName string, ciCtx *utils.CIContext, legacy bool) ([]*godo.App, error) {
+	var found []*godo.App
+	app, err := utils.FindAppByName(ctx, apps, appName)
+	if err != nil {
+		return nil, err
+	}
+	if app != nil {
+		found = append(found, app)
+	}
+
+	legacyName := utils.LegacyAppName(ciCtx.Branch)
+	if !legacy || legacyName == appName {
+		return found, nil
+	}
+	legacyApp, err := utils.FindAppByName(ctx, apps, legacyName)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case legacyApp == nil:
+	case utils.IsPreviewFor(legacyApp.GetSpec(), ciCtx):
+		a.Infof("found app %q named after the legacy naming scheme of preview apps", legacyName)
+		found = append(found, legacyApp)
+	default:
+		a.Infof("app %q is named like a legacy preview app but doesn't look like a preview of branch %q of %s, skipping it", legacyName, ciCtx.Branch, ciCtx.Repository)
+	}
+	return found, nil
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/digitalocean/godo"
+)
+
+// concurrencyPolicy defines how to handle a deployment that's already in
+// progress on the app when deploying.
+type concurrencyPolicy string
+
+const (
+	// concurrencyPolicyWait waits for the deployment in progress to finish.
+	concurrencyPolicyWait concurrencyPolicy = "wait"
+	// concurrencyPolicyCancel cancels the deployment in progress.
+	concurrencyPolicyCancel concurrencyPolicy = "cancel"
+	// concurrencyPolicyFail fails the deployment.
+	concurrencyPolicyFail concurrencyPolicy = "fail"
+)
+
+// errSuperseded is returned if the deployment was superseded by a newer one
+// before it finished.
+var errSuperseded = errors.New("superseded by a newer deployment")
+
+// parseConcurrencyPolicy parses the given concurrency policy.
+func parseConcurrencyPolicy(s string) (concurrencyPolicy, error) {
+	switch p := concurrencyPolicy(s); p {
+	case concurrencyPolicyWait, concurrencyPolicyCancel, concurrencyPolicyFail:
+		return p, nil
+	}
+	return "", fmt.Errorf("invalid concurrency policy %q, must be one of %q, %q or %q", s, concurrencyPolicyWait, concurrencyPolicyCancel, concurrencyPolicyFail)
+}
+
+// handleInProgressDeployment applies the configured concurrency policy if the
+// given app has a deployment in progress.
+func (d *deployer) handleInProgressDeployment(ctx context.Context, app *godo.App) error {
+	inProgress := app.GetInProgressDeployment()
+	if inProgress == nil || isInTerminalPhase(inProgress) {
+		return nil
+	}
+
+	switch d.inputs.concurrencyPolicy {
+	case concurrencyPolicyFail:
+		return fmt.Errorf("deployment %s is already in progress on app %q", inProgress.GetID(), app.GetSpec().GetName())
+	case concurrencyPolicyCancel:
+		d.action.Infof("canceling deployment %s that is already in progress", inProgress.GetID())
+		if _, _, err := d.deployments.CancelDeployment(ctx, app.GetID(), inProgress.GetID()); err != nil {
+			return fmt.Errorf("failed to cancel deployment %s: %w", inProgress.GetID(), err)
+		}
+	default:
+		d.action.Infof("waiting for deployment %s that is already in progress to finish", inProgress.GetID())
+	}
+
+	dep, err := d.waitForOtherDeployment(ctx, app.GetID(), inProgress.GetID())
+	if err != nil {
+		return fmt.Errorf("failed to wait for deployment %s to finish: %w", inProgress.GetID(), err)
+	}
+	d.action.Infof("deployment %s finished in phase: %s", dep.GetID(), dep.GetPhase())
+	return nil
+}
+
+// waitForOtherDeployment waits for a deployment not created by this action to
+// be in a terminal state. Unlike waitForDeploymentTerminal, it neither logs the
+// deployment's progress nor enforces any timeouts besides the context's.
+func (d *deployer) waitForOtherDeployment(ctx context.Context, appID, deploymentID string) (*godo.Deployment, error) {
+	t := time.NewTicker(pollInterval)
+	defer t.Stop()
+
+	for {
+		dep, _, err := d.apps.GetDeployment(ctx, appID, deploymentID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get deployment: %w", err)
+		}
+		if isInTerminalPhase(dep) {
+			return dep, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-t.C:
+		}
+	}
+}
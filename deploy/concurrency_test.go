@@ -0,0 +1,112 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/digitalocean/godo"
+	gha "github.com/sethvargo/go-githubactions"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseConcurrencyPolicy(t *testing.T) {
+	for _, policy := range []string{"wait", "cancel", "fail"} {
+		got, err := parseConcurrencyPolicy(policy)
+		require.NoError(t, err)
+		require.Equal(t, concurrencyPolicy(policy), got)
+	}
+
+	_, err := parseConcurrencyPolicy("queue")
+	require.EqualError(t, err, `invalid concurrency policy "queue", must be one of "wait", "cancel" or "fail"`)
+}
+
+func TestHandleInProgressDeployment(t *testing.T) {
+	ctx := context.Background()
+	appID := "app-id"
+	otherID := "other-id"
+
+	tests := []struct {
+		name         string
+		app          *godo.App
+		policy       concurrencyPolicy
+		expectCancel bool
+		expectWait   bool
+		expectedErr  string
+		expectedLogs []byte
+	}{{
+		name:   "no deployment in progress",
+		app:    &godo.App{ID: appID},
+		policy: concurrencyPolicyFail,
+	}, {
+		name: "wait",
+		app: &godo.App{ID: appID, InProgressDeployment: &godo.Deployment{
+			ID:    otherID,
+			Phase: godo.DeploymentPhase_Building,
+		}},
+		policy:     concurrencyPolicyWait,
+		expectWait: true,
+		expectedLogs: []byte(`waiting for deployment other-id that is already in progress to finish
+deployment other-id finished in phase: ACTIVE
+`),
+	}, {
+		name: "cancel",
+		app: &godo.App{ID: appID, InProgressDeployment: &godo.Deployment{
+			ID:    otherID,
+			Phase: godo.DeploymentPhase_Deploying,
+		}},
+		policy:       concurrencyPolicyCancel,
+		expectCancel: true,
+		expectWait:   true,
+		expectedLogs: []byte(`canceling deployment other-id that is already in progress
+deployment other-id finished in phase: ACTIVE
+`),
+	}, {
+		name: "fail",
+		app: &godo.App{ID: appID, Spec: &godo.AppSpec{Name: "foo"}, InProgressDeployment: &godo.Deployment{
+			ID:    otherID,
+			Phase: godo.DeploymentPhase_Building,
+		}},
+		policy:      concurrencyPolicyFail,
+		expectedErr: `deployment other-id is already in progress on app "foo"`,
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			as := &mockedAppsService{}
+			if test.expectWait {
+				as.On("GetDeployment", ctx, appID, otherID).Return(&godo.Deployment{
+					ID:    otherID,
+					Phase: godo.DeploymentPhase_Building,
+				}, &godo.Response{}, nil).Once()
+				as.On("GetDeployment", ctx, appID, otherID).Return(&godo.Deployment{
+					ID:    otherID,
+					Phase: godo.DeploymentPhase_Active,
+				}, &godo.Response{}, nil).Once()
+			}
+			ds := &mockedDeploymentsService{}
+			if test.expectCancel {
+				ds.On("CancelDeployment", mock.Anything, appID, otherID).Return(&godo.Deployment{}, &godo.Response{}, nil).Once()
+			}
+
+			var actionLogs bytes.Buffer
+			d := &deployer{
+				action:      gha.New(gha.WithWriter(&actionLogs)),
+				apps:        as,
+				deployments: ds,
+				inputs:      inputs{concurrencyPolicy: test.policy},
+			}
+			err := d.handleInProgressDeployment(ctx, test.app)
+			if test.expectedErr != "" {
+				require.EqualError(t, err, test.expectedErr)
+			} else {
+				require.NoError(t, err)
+			}
+			require.Equal(t, test.expectedLogs, actionLogs.Bytes())
+
+			as.AssertExpectations(t)
+			ds.AssertExpectations(t)
+		})
+	}
+}
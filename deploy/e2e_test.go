@@ -0,0 +1,231 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/digitalocean/app_action/internal/fakeapi"
+	"github.com/digitalocean/app_action/utils"
+	"github.com/digitalocean/godo"
+	"github.com/stretchr/testify/require"
+)
+
+// runMainEnv makes the test binary run the action instead of the tests.
+const runMainEnv = "APP_ACTION_TEST_RUN_MAIN"
+
+func TestMain(m *testing.M) {
+	if os.Getenv(runMainEnv) == "true" {
+		main()
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// runAction runs the action as a CLI against the given fake API and returns
+// its outputs, its logs and its exit code.
+func runAction(t *testing.T, srv *fakeapi.Server, args ...string) (map[string]string, string, int) {
+	t.Helper()
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(os.Args[0], args...)
+	cmd.Env = []string{
+		runMainEnv + "=true",
+		"GITHUB_ACTIONS=false",
+		"APP_ACTION_TOKEN=token",
+		"APP_ACTION_API_BASE_URL=" + srv.URL,
+	}
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	code := 0
+	if err := cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		require.ErrorAs(t, err, &exitErr, "stderr: %s", stderr.String())
+		code = exitErr.ExitCode()
+	}
+
+	outputs := make(map[string]string)
+	require.NoError(t, json.Unmarshal(stdout.Bytes(), &outputs), "stdout: %s", stdout.String())
+	return outputs, stderr.String(), code
+}
+
+// writeSpec writes the given app spec to a temporary file and returns its path.
+func writeSpec(t *testing.T, spec string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "app.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(spec), 0o600))
+	return path
+}
+
+const e2eSpec = `
+name: foo
+services:
+- name: web
+  image:
+    registry_type: DOCR
+    repository: web
+    tag: %s
+`
+
+func TestEndToEnd(t *testing.T) {
+	srv := fakeapi.New()
+	defer srv.Close()
+
+	outputs, logs, code := runAction(t, srv, "--app-spec-location", writeSpec(t, fmtSpec("v1")), "--print-build-logs")
+	require.Equal(t, 0, code, logs)
+	require.Equal(t, "true", outputs["created"])
+	require.Equal(t, "foo", outputs["app_name"])
+	require.Equal(t, string(godo.DeploymentPhase_Active), outputs["deployment_phase"])
+	require.Equal(t, "BUILD web: done\n", outputs["build_logs"])
+	require.Contains(t, logs, "BUILD web: done")
+	require.NotEmpty(t, outputs["live_url"])
+	// The spec is only validated on request.
+	require.NotContains(t, outputs, "cost_estimate")
+	appID := outputs["app_id"]
+
+	outputs, logs, code = runAction(t, srv, "--app-spec-location", writeSpec(t, fmtSpec("v2")), "--health-checks", "[{path: /health, body_contains: live}]")
+	require.Equal(t, 0, code, logs)
+	require.Equal(t, "false", outputs["created"])
+	require.Equal(t, appID, outputs["app_id"])
+
+	deployments := srv.Deployments(appID)
+	require.Len(t, deployments, 2)
+	require.Equal(t, "v2", deployments[0].GetSpec().GetServices()[0].GetImage().GetTag())
+}
+
+func TestEndToEndFailedDeployment(t *testing.T) {
+	srv := fakeapi.New()
+	defer srv.Close()
+	srv.FailDeployments("foo")
+
+	outputs, logs, code := runAction(t, srv, "--app-spec-location", writeSpec(t, fmtSpec("v1")))
+	require.Equal(t, 1, code)
+	require.Equal(t, string(godo.DeploymentPhase_Error), outputs["deployment_phase"])
+	require.Contains(t, logs, "step deploy failed")
+}
+
+func TestEndToEndSupersededDeployment(t *testing.T) {
+	tests := []struct {
+		name         string
+		args         []string
+		expectedCode int
+	}{{
+		name:         "fails by default",
+		expectedCode: 1,
+	}, {
+		name: "warns if requested",
+		args: []string{"--fail-on-superseded=false"},
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctx := context.Background()
+			srv := fakeapi.New(fakeapi.WithPhaseDuration(50 * time.Millisecond))
+			defer srv.Close()
+			client, err := utils.NewClient("token", "test", utils.ClientConfig{BaseURL: srv.URL})
+			require.NoError(t, err)
+			app, _, err := client.Apps.Create(ctx, &godo.AppCreateRequest{Spec: &godo.AppSpec{
+				Name: "foo",
+				Services: []*godo.AppServiceSpec{{
+					Name:  "web",
+					Image: &godo.ImageSourceSpec{RegistryType: godo.ImageSourceSpecRegistryType_DOCR, Repository: "web", Tag: "v1"},
+				}},
+			}})
+			require.NoError(t, err)
+			require.Eventually(t, func() bool {
+				return srv.Deployments(app.GetID())[0].GetPhase() == godo.DeploymentPhase_Active
+			}, time.Second, 10*time.Millisecond)
+
+			// Supersede the action's deployment as soon as it's created.
+			done := make(chan struct{})
+			defer close(done)
+			go func() {
+				for len(srv.Deployments(app.GetID())) < 2 {
+					select {
+					case <-done:
+						return
+					case <-time.After(10 * time.Millisecond):
+					}
+				}
+				_, _, _ = client.Apps.CreateDeployment(ctx, app.GetID())
+			}()
+
+			args := append([]string{"--app-spec-location", writeSpec(t, fmtSpec("v1")), "--mode", "redeploy"}, test.args...)
+			outputs, logs, code := runAction(t, srv, args...)
+			require.Equal(t, test.expectedCode, code, logs)
+			require.Equal(t, "true", outputs["superseded"])
+			require.Contains(t, logs, "superseded by a newer deployment")
+		})
+	}
+}
+
+func TestEndToEndInvalidSpec(t *testing.T) {
+	srv := fakeapi.New()
+	defer srv.Close()
+
+	_, logs, code := runAction(t, srv, "--app-spec-location", writeSpec(t, "name: Invalid_Name\n"), "--validate-spec")
+	require.Equal(t, 1, code)
+	require.Contains(t, logs, "app spec is invalid")
+	require.Empty(t, srv.Apps())
+}
+
+func TestEndToEndUnreachableAPI(t *testing.T) {
+	srv := fakeapi.New()
+	srv.Close()
+
+	_, logs, code := runAction(t, srv, "--app-spec-location", writeSpec(t, fmtSpec("v1")), "--api-max-retries", "0")
+	require.Equal(t, 1, code)
+	require.Contains(t, logs, "failed to deploy")
+}
+
+// fmtSpec returns the end-to-end test spec deploying the given image tag.
+func fmtSpec(tag string) string {
+	return fmt.Sprintf(e2eSpec, tag)
+}
+
+func TestEndToEndProxy(t *testing.T) {
+	srv := fakeapi.New()
+	defer srv.Close()
+
+	var mu sync.Mutex
+	var proxied []string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		proxied = append(proxied, r.URL.Path)
+		mu.Unlock()
+
+		// Forward the request as is, it carries the absolute URL of the target.
+		r.RequestURI = ""
+		resp, err := http.DefaultTransport.RoundTrip(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		defer resp.Body.Close()
+		maps.Copy(w.Header(), resp.Header)
+		w.WriteHeader(resp.StatusCode)
+		_, _ = io.Copy(w, resp.Body)
+	}))
+	defer proxy.Close()
+
+	_, logs, code := runAction(t, srv, "--app-spec-location", writeSpec(t, fmtSpec("v1")), "--proxy-url", proxy.URL)
+	require.Equal(t, 0, code, logs)
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.Contains(t, proxied, "/v2/apps")
+	// The historic logs are fetched through the proxy as well.
+	require.Contains(t, proxied, "/logs/deployment-1/BUILD")
+}
@@ -0,0 +1,23 @@
+package main
+
+import "fmt"
+
+// forkPolicy defines whether previews of pull requests from forks are deployed.
+type forkPolicy string
+
+const (
+	// forkPolicyDeny fails the deployment of previews of pull requests from forks.
+	forkPolicyDeny forkPolicy = "deny"
+	// forkPolicyAllow deploys previews of pull requests from forks, building
+	// the fork's branch.
+	forkPolicyAllow forkPolicy = "allow"
+)
+
+// parseForkPolicy parses the given fork policy.
+func parseForkPolicy(s string) (forkPolicy, error) {
+	switch p := forkPolicy(s); p {
+	case forkPolicyDeny, forkPolicyAllow:
+		return p, nil
+	}
+	return "", fmt.Errorf("invalid fork policy %q, must be one of %q or %q", s, forkPolicyDeny, forkPolicyAllow)
+}
@@ -0,0 +1,18 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseForkPolicy(t *testing.T) {
+	for _, policy := range []string{"deny", "allow"} {
+		got, err := parseForkPolicy(policy)
+		require.NoError(t, err)
+		require.Equal(t, forkPolicy(policy), got)
+	}
+
+	_, err := parseForkPolicy("")
+	require.EqualError(t, err, `invalid fork policy "", must be one of "deny" or "allow"`)
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// healthCheckRetryInterval is the time waited between attempts of a failing health check.
+	healthCheckRetryInterval = 5 * time.Second
+	// healthCheckRequestTimeout bounds a single health check request.
+	healthCheckRequestTimeout = 10 * time.Second
+	// healthCheckMaxBodySize is the maximum amount of bytes of a response body
+	// that are searched for the expected content.
+	healthCheckMaxBodySize = 1 << 20
+)
+
+// healthCheck is a check of a single path of the live app.
+type healthCheck struct {
+	// Path is the path to check, relative to the app's live URL.
+	Path string `json:"path"`
+	// Status is the expected status code. Defaults to 200.
+	Status int `json:"status,omitempty"`
+	// BodyContains is an optional substring the response body has to contain.
+	BodyContains string `json:"body_contains,omitempty"`
+}
+
+// parseHealthChecks parses the health checks from their YAML representation.
+func parseHealthChecks(s string) ([]healthCheck, error) {
+	if strings.TrimSpace(s) == "" {
+		return nil, nil
+	}
+
+	var checks []healthCheck
+	if err := yaml.UnmarshalStrict([]byte(s), &checks); err != nil {
+		return nil, fmt.Errorf("failed to parse health checks: %w", err)
+	}
+	for i := range checks {
+		if checks[i].Path == "" {
+			return nil, fmt.Errorf("health check %d is missing a path", i)
+		}
+		if checks[i].Status == 0 {
+			checks[i].Status = http.StatusOK
+		}
+	}
+	return checks, nil
+}
+
+// verifyHealth runs all configured health checks against the given live URL.
+// Failing checks are retried until they either succeed, run out of retries or
+// the overall health check timeout is hit.
+func (d *deployer) verifyHealth(ctx context.Context, liveURL string) error {
+	if d.inputs.healthCheckTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, d.inputs.healthCheckTimeout)
+		defer cancel()
+	}
+
+	for _, check := range d.inputs.healthChecks {
+		if err := d.runHealthCheck(ctx, liveURL, check); err != nil {
+			return fmt.Errorf("health check of %q failed: %w", check.Path, err)
+		}
+		d.action.Infof("health check of %q succeeded", check.Path)
+	}
+	return nil
+}
+
+// runHealthCheck runs the given health check, retrying it if necessary.
+func (d *deployer) runHealthCheck(ctx context.Context, liveURL string, check healthCheck) error {
+	for attempt := 0; ; attempt++ {
+		err := d.checkHealthOnce(ctx, liveURL, check)
+		if err == nil {
+			return nil
+		}
+		if attempt >= d.inputs.healthCheckRetries {
+			return err
+		}
+		d.action.Infof("health check of %q failed, retrying: %v", check.Path, err)
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%w (last error: %w)", ctx.Err(), err)
+		case <-time.After(healthCheckRetryInterval):
+		}
+	}
+}
+
+// checkHealthOnce runs a single attempt of the given health check.
+func (d *deployer) checkHealthOnce(ctx context.Context, liveURL string, check healthCheck) error {
+	ctx, cancel := context.WithTimeout(ctx, healthCheckRequestTimeout)
+	defer cancel()
+
+	url := strings.TrimRight(liveURL, "/") + "/" + strings.TrimLeft(check.Path, "/")
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	resp, err := d.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != check.Status {
+		return fmt.Errorf("expected status %d but got %d", check.Status, resp.StatusCode)
+	}
+	if check.BodyContains == "" {
+		return nil
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, healthCheckMaxBodySize))
+	if err != nil {
+		return fmt.Errorf("failed to read response body: %w", err)
+	}
+	if !strings.Contains(string(body), check.BodyContains) {
+		return errors.New("response body doesn't contain the expected content")
+	}
+	return nil
+}
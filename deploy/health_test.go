@@ -0,0 +1,145 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	gha "github.com/sethvargo/go-githubactions"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseHealthChecks(t *testing.T) {
+	tests := []struct {
+		name     string
+		in       string
+		expected []healthCheck
+		err      bool
+	}{{
+		name: "empty",
+		in:   " ",
+	}, {
+		name: "defaults",
+		in: `
+- path: /healthz
+- path: /api
+  status: 204
+  body_contains: ok
+`,
+		expected: []healthCheck{{
+			Path:   "/healthz",
+			Status: http.StatusOK,
+		}, {
+			Path:         "/api",
+			Status:       http.StatusNoContent,
+			BodyContains: "ok",
+		}},
+	}, {
+		name: "missing path",
+		in:   `[{status: 200}]`,
+		err:  true,
+	}, {
+		name: "unknown field",
+		in:   `[{path: /, code: 200}]`,
+		err:  true,
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := parseHealthChecks(test.in)
+			if test.err {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+			require.Equal(t, test.expected, got)
+		})
+	}
+}
+
+func TestVerifyHealth(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/healthz":
+			w.Write([]byte(`{"ok":true}`))
+		case "/broken":
+			w.WriteHeader(http.StatusInternalServerError)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name         string
+		inputs       inputs
+		expectedErr  string
+		expectedLogs []byte
+	}{{
+		name: "healthy",
+		inputs: inputs{healthChecks: []healthCheck{{
+			Path:   "/healthz",
+			Status: http.StatusOK,
+		}, {
+			Path:         "healthz",
+			Status:       http.StatusOK,
+			BodyContains: `"ok":true`,
+		}, {
+			Path:   "/missing",
+			Status: http.StatusNotFound,
+		}}},
+		expectedLogs: []byte(`health check of "/healthz" succeeded
+health check of "healthz" succeeded
+health check of "/missing" succeeded
+`),
+	}, {
+		name: "unexpected status",
+		inputs: inputs{healthChecks: []healthCheck{{
+			Path:   "/broken",
+			Status: http.StatusOK,
+		}}},
+		expectedErr: `health check of "/broken" failed: expected status 200 but got 500`,
+	}, {
+		name: "unexpected body",
+		inputs: inputs{healthChecks: []healthCheck{{
+			Path:         "/healthz",
+			Status:       http.StatusOK,
+			BodyContains: "healthy",
+		}}},
+		expectedErr: `health check of "/healthz" failed: response body doesn't contain the expected content`,
+	}, {
+		name: "retries exceed budget",
+		inputs: inputs{
+			healthChecks: []healthCheck{{
+				Path:   "/broken",
+				Status: http.StatusOK,
+			}},
+			healthCheckRetries: 3,
+			healthCheckTimeout: 50 * time.Millisecond,
+		},
+		expectedErr: `health check of "/broken" failed: context deadline exceeded (last error: expected status 200 but got 500)`,
+		expectedLogs: []byte(`health check of "/broken" failed, retrying: expected status 200 but got 500
+`),
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var actionLogs bytes.Buffer
+			d := &deployer{
+				action:     gha.New(gha.WithWriter(&actionLogs)),
+				httpClient: srv.Client(),
+				inputs:     test.inputs,
+			}
+			err := d.verifyHealth(context.Background(), srv.URL+"/")
+			if test.expectedErr != "" {
+				require.EqualError(t, err, test.expectedErr)
+			} else {
+				require.NoError(t, err)
+			}
+			require.Equal(t, test.expectedLogs, actionLogs.Bytes())
+		})
+	}
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/digitalocean/godo"
+)
+
+// hasRoutableComponents returns whether or not the app described by the spec
+// serves HTTP traffic and thus eventually gets a live URL.
+func hasRoutableComponents(spec *godo.AppSpec) bool {
+	for _, rule := range spec.GetIngress().GetRules() {
+		if rule.GetComponent().GetName() != "" {
+			return true
+		}
+	}
+	for _, svc := range spec.GetServices() {
+		// Services without an HTTP port but with internal ports are only
+		// reachable from within the app. All others default to HTTP port 8080.
+		if svc.GetHTTPPort() != 0 || len(svc.GetInternalPorts()) == 0 {
+			return true
+		}
+	}
+	return len(spec.GetStaticSites()) > 0 || len(spec.GetFunctions()) > 0
+}
+
+// componentStatuses returns the status of each component of the spec in the
+// given deployment, keyed by component name. The status of a component is the
+// status of the last deployment step concerning it.
+func componentStatuses(spec *godo.AppSpec, dep *godo.Deployment) map[string]godo.DeploymentProgressStepStatus {
+	stepStatuses := make(map[string]godo.DeploymentProgressStepStatus)
+	for _, step := range flattenSteps(dep.GetProgress().GetSteps()) {
+		if step.Component != "" {
+			stepStatuses[step.Component] = step.Status
+		}
+	}
+
+	statuses := make(map[string]godo.DeploymentProgressStepStatus)
+	_ = spec.ForEachAppComponentSpec(func(c godo.AppComponentSpec) error {
+		if _, ok := c.(*godo.AppDatabaseSpec); ok {
+			// Databases are not part of the deployment's steps.
+			return nil
+		}
+		status, ok := stepStatuses[c.GetName()]
+		if !ok {
+			status = godo.DeploymentProgressStepStatus_Unknown
+		}
+		statuses[c.GetName()] = status
+		return nil
+	})
+	return statuses
+}
+
+// reportComponentStatuses logs the status of each component in the given
+// deployment and surfaces them as the "component_statuses" output.
+func (d *deployer) reportComponentStatuses(spec *godo.AppSpec, dep *godo.Deployment) error {
+	statuses := componentStatuses(spec, dep)
+	for _, name := range sortedKeys(statuses, nil) {
+		d.action.Infof("component %s is %s", name, statuses[name])
+	}
+
+	statusesJSON, err := json.Marshal(statuses)
+	if err != nil {
+		return fmt.Errorf("failed to marshal component statuses: %w", err)
+	}
+	d.action.SetOutput("component_statuses", string(statusesJSON))
+	return nil
+}
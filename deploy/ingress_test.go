@@ -0,0 +1,94 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/digitalocean/godo"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHasRoutableComponents(t *testing.T) {
+	tests := []struct {
+		name     string
+		spec     *godo.AppSpec
+		expected bool
+	}{{
+		name: "no components",
+		spec: &godo.AppSpec{},
+	}, {
+		name: "workers and jobs",
+		spec: &godo.AppSpec{
+			Workers: []*godo.AppWorkerSpec{{Name: "worker"}},
+			Jobs:    []*godo.AppJobSpec{{Name: "job"}},
+		},
+	}, {
+		name: "internal service",
+		spec: &godo.AppSpec{
+			Services: []*godo.AppServiceSpec{{Name: "internal", InternalPorts: []int64{8080}}},
+		},
+	}, {
+		name: "service with default HTTP port",
+		spec: &godo.AppSpec{
+			Services: []*godo.AppServiceSpec{{Name: "web"}},
+		},
+		expected: true,
+	}, {
+		name: "service with HTTP and internal ports",
+		spec: &godo.AppSpec{
+			Services: []*godo.AppServiceSpec{{Name: "web", HTTPPort: 80, InternalPorts: []int64{8080}}},
+		},
+		expected: true,
+	}, {
+		name: "static site",
+		spec: &godo.AppSpec{
+			StaticSites: []*godo.AppStaticSiteSpec{{Name: "site"}},
+		},
+		expected: true,
+	}, {
+		name: "ingress rule",
+		spec: &godo.AppSpec{
+			Ingress: &godo.AppIngressSpec{Rules: []*godo.AppIngressSpecRule{{
+				Component: &godo.AppIngressSpecRuleRoutingComponent{Name: "fn"},
+			}}},
+		},
+		expected: true,
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			require.Equal(t, test.expected, hasRoutableComponents(test.spec))
+		})
+	}
+}
+
+func TestComponentStatuses(t *testing.T) {
+	spec := &godo.AppSpec{
+		Workers:   []*godo.AppWorkerSpec{{Name: "worker"}},
+		Jobs:      []*godo.AppJobSpec{{Name: "job"}},
+		Databases: []*godo.AppDatabaseSpec{{Name: "db"}},
+	}
+	dep := &godo.Deployment{Progress: &godo.DeploymentProgress{
+		Steps: []*godo.DeploymentProgressStep{{
+			Name:   "build",
+			Status: godo.DeploymentProgressStepStatus_Success,
+			Steps: []*godo.DeploymentProgressStep{{
+				Name:          "worker",
+				ComponentName: "worker",
+				Status:        godo.DeploymentProgressStepStatus_Success,
+			}},
+		}, {
+			Name:   "deploy",
+			Status: godo.DeploymentProgressStepStatus_Error,
+			Steps: []*godo.DeploymentProgressStep{{
+				Name:          "worker",
+				ComponentName: "worker",
+				Status:        godo.DeploymentProgressStepStatus_Error,
+			}},
+		}},
+	}}
+
+	require.Equal(t, map[string]godo.DeploymentProgressStepStatus{
+		"worker": godo.DeploymentProgressStepStatus_Error,
+		"job":    godo.DeploymentProgressStepStatus_Unknown,
+	}, componentStatuses(spec, dep))
+}
@@ -1,25 +1,53 @@
 package main
 
 import (
+	"fmt"
+	"time"
+
 	"github.com/digitalocean/app_action/utils"
-	gha "github.com/sethvargo/go-githubactions"
 )
 
 // inputs are the inputs for the action.
 type inputs struct {
-	token             string
-	appSpecLocation   string
-	projectID         string
-	appName           string
-	printBuildLogs    bool
-	printDeployLogs   bool
-	deployPRPreview   bool
-	preservePRDomains bool
+	token               string
+	appSpecLocation     string
+	projectID           string
+	appName             string
+	printBuildLogs      bool
+	printDeployLogs     bool
+	deployPRPreview     bool
+	preservePRDomains   bool
+	previewNameTemplate string
+	forkPRPreviews      forkPolicy
+	previewResources    *utils.PreviewResourcePolicy
+	deployTimeout       time.Duration
+	buildPhaseTimeout   time.Duration
+	deployPhaseTimeout  time.Duration
+	dryRun              bool
+	validateSpec        bool
+	rollbackOnFailure   bool
+	healthChecks        []healthCheck
+	healthCheckRetries  int
+	healthCheckTimeout  time.Duration
+	jobSummary          bool
+	redactAllEnvs       bool
+	cancelOnAbort       bool
+	concurrencyPolicy   concurrencyPolicy
+	failOnSuperseded    bool
+	apiMaxRetries       int
+	apiMaxBackoff       time.Duration
+	apiBaseURL          string
+	proxyURL            string
+	caBundle            string
+	skipIfUnchanged     bool
+	mode                deployMode
+	forceBuild          bool
 }
 
 // getInputs gets the inputs for the action.
-func getInputs(a *gha.Action) (inputs, error) {
+func getInputs(a utils.Runtime) (inputs, error) {
 	var in inputs
+	var healthChecks, concurrencyPolicy, mode, forkPRPreviews, previewResources string
 	for _, err := range []error{
 		utils.InputAsString(a, "token", true, &in.token),
 		utils.InputAsString(a, "app_spec_location", false, &in.appSpecLocation),
@@ -29,10 +57,61 @@ func getInputs(a *gha.Action) (inputs, error) {
 		utils.InputAsBool(a, "print_deploy_logs", true, &in.printDeployLogs),
 		utils.InputAsBool(a, "deploy_pr_preview", true, &in.deployPRPreview),
 		utils.InputAsBool(a, "preserve_pr_domains", true, &in.preservePRDomains),
+		utils.InputAsString(a, "preview_name_template", false, &in.previewNameTemplate),
+		utils.InputAsString(a, "fork_pr_previews", true, &forkPRPreviews),
+		utils.InputAsString(a, "preview_resource_policy", false, &previewResources),
+		utils.InputAsDuration(a, "deploy_timeout", false, &in.deployTimeout),
+		utils.InputAsDuration(a, "build_phase_timeout", false, &in.buildPhaseTimeout),
+		utils.InputAsDuration(a, "deploy_phase_timeout", false, &in.deployPhaseTimeout),
+		utils.InputAsBool(a, "dry_run", true, &in.dryRun),
+		utils.InputAsBool(a, "validate_spec", true, &in.validateSpec),
+		utils.InputAsBool(a, "rollback_on_failure", true, &in.rollbackOnFailure),
+		utils.InputAsString(a, "health_checks", false, &healthChecks),
+		utils.InputAsInt(a, "health_check_retries", false, &in.healthCheckRetries),
+		utils.InputAsDuration(a, "health_check_timeout", false, &in.healthCheckTimeout),
+		utils.InputAsBool(a, "job_summary", true, &in.jobSummary),
+		utils.InputAsBool(a, "redact_all_envs", true, &in.redactAllEnvs),
+		utils.InputAsBool(a, "cancel_on_abort", true, &in.cancelOnAbort),
+		utils.InputAsString(a, "concurrency_policy", true, &concurrencyPolicy),
+		utils.InputAsBool(a, "fail_on_superseded", true, &in.failOnSuperseded),
+		utils.InputAsInt(a, "api_max_retries", false, &in.apiMaxRetries),
+		utils.InputAsDuration(a, "api_max_backoff", false, &in.apiMaxBackoff),
+		utils.InputAsString(a, "api_base_url", false, &in.apiBaseURL),
+		utils.InputAsString(a, "proxy_url", false, &in.proxyURL),
+		utils.InputAsString(a, "ca_bundle", false, &in.caBundle),
+		utils.InputAsBool(a, "skip_if_unchanged", true, &in.skipIfUnchanged),
+		utils.InputAsString(a, "mode", true, &mode),
+		utils.InputAsBool(a, "force_build", true, &in.forceBuild),
 	} {
 		if err != nil {
 			return in, err
 		}
 	}
+
+	var err error
+	in.healthChecks, err = parseHealthChecks(healthChecks)
+	if err != nil {
+		return in, err
+	}
+	in.concurrencyPolicy, err = parseConcurrencyPolicy(concurrencyPolicy)
+	if err != nil {
+		return in, err
+	}
+	in.mode, err = parseDeployMode(mode)
+	if err != nil {
+		return in, err
+	}
+	in.forkPRPreviews, err = parseForkPolicy(forkPRPreviews)
+	if err != nil {
+		return in, err
+	}
+	in.previewResources, err = utils.ParsePreviewResourcePolicy(previewResources)
+	if err != nil {
+		return in, err
+	}
+	if in.forceBuild && in.mode != deployModeRedeploy {
+		// Updating the spec always triggers a regular deployment.
+		return in, fmt.Errorf("force_build is only supported in mode %q", deployModeRedeploy)
+	}
 	return in, nil
 }
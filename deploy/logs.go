@@ -0,0 +1,133 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/digitalocean/godo"
+)
+
+const (
+	// liveLogsGracePeriod is the time a live log stream may still deliver lines
+	// after the deployment moved on to the next phase.
+	liveLogsGracePeriod = 5 * time.Second
+	// liveLogsMaxLineSize is the maximum size of a single log line.
+	liveLogsMaxLineSize = 1 << 20
+)
+
+// liveLogType returns the type of logs to stream live during the given phase,
+// if they should be printed.
+func (d *deployer) liveLogType(phase godo.DeploymentPhase) (godo.AppLogType, bool) {
+	switch phase {
+	case godo.DeploymentPhase_Building:
+		return godo.AppLogTypeBuild, d.inputs.printBuildLogs
+	case godo.DeploymentPhase_Deploying:
+		return godo.AppLogTypeDeploy, d.inputs.printDeployLogs
+	}
+	return "", false
+}
+
+// streamLiveLogs prints the live logs of the given type while the deployment
+// is in the given phase. It blocks until the stream ends, the deployment leaves
+// the phase or the context is done.
+// It returns whether or not the logs were streamed completely, i.e. the stream
+// delivered logs and ended normally. If not, for example because the stream
+// was cut, the caller is expected to fall back to the historic logs.
+// As the stream blocks the caller's poll loop, step transitions happening
+// meanwhile are only logged once the stream ended, each step with its latest
+// status.
+func (d *deployer) streamLiveLogs(ctx context.Context, appID, deploymentID string, phase godo.DeploymentPhase, logType godo.AppLogType) bool {
+	logs, _, err := d.apps.GetLogs(ctx, appID, deploymentID, "", logType, true, -1)
+	if err != nil || !isHTTPURL(logs.LiveURL) {
+		// Live logs might not be available (yet) or only via protocols we don't
+		// support. The historic logs will be printed instead.
+		return false
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	if timeout := d.phaseTimeout(phase); timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	go d.cancelOnPhaseChange(ctx, cancel, appID, deploymentID, phase)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, logs.LiveURL, nil)
+	if err != nil {
+		return false
+	}
+	resp, err := d.httpClient.Do(req)
+	if err != nil {
+		return false
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return false
+	}
+
+	var printed bool
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(nil, liveLogsMaxLineSize)
+	for scanner.Scan() {
+		if !printed {
+			// Only open the group once there are logs to not leave an empty one
+			// behind before the historic logs.
+			d.action.Group(fmt.Sprintf("%s logs", strings.ToLower(string(logType))))
+			defer d.action.EndGroup()
+			printed = true
+		}
+		d.action.Infof("%s", scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		// The stream might have been cut before all logs were delivered, for
+		// example once the phase ended.
+		if printed {
+			d.action.Infof("the live %s logs ended early, the complete logs are printed once the deployment finished", strings.ToLower(string(logType)))
+		}
+		return false
+	}
+	return printed
+}
+
+// cancelOnPhaseChange calls cancel once the deployment left the given phase
+// and the grace period passed. It must not write to the action's log as it
+// runs concurrently to the log stream.
+func (d *deployer) cancelOnPhaseChange(ctx context.Context, cancel context.CancelFunc, appID, deploymentID string, phase godo.DeploymentPhase) {
+	t := time.NewTicker(pollInterval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+
+		dep, _, err := d.apps.GetDeployment(ctx, appID, deploymentID)
+		if err != nil || dep.GetPhase() == phase {
+			// Polling errors are surfaced by the main poll loop after the stream ended.
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+		case <-time.After(liveLogsGracePeriod):
+			cancel()
+		}
+		return
+	}
+}
+
+// isHTTPURL returns whether or not the given URL can be fetched via plain HTTP(S).
+func isHTTPURL(u string) bool {
+	parsed, err := url.Parse(u)
+	if err != nil {
+		return false
+	}
+	return parsed.Scheme == "http" || parsed.Scheme == "https"
+}
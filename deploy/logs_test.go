@@ -0,0 +1,132 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/digitalocean/godo"
+	gha "github.com/sethvargo/go-githubactions"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStreamLiveLogs(t *testing.T) {
+	ctx := context.Background()
+	appID := "app-id"
+	deploymentID := "deployment-id"
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/empty":
+			// The stream was cut before any logs were written.
+			return
+		case "/cut":
+			// The stream was cut after some logs were written.
+			w.Header().Set("Content-Length", "100")
+			w.Write([]byte("step 1\n"))
+			return
+		}
+		w.Write([]byte("step 1\nstep 2\n"))
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name         string
+		logs         *godo.AppLogs
+		logsErr      error
+		streamed     bool
+		expectedLogs []byte
+	}{{
+		name:     "streams live logs",
+		logs:     &godo.AppLogs{LiveURL: srv.URL},
+		streamed: true,
+		expectedLogs: []byte(`::group::build logs
+step 1
+step 2
+::endgroup::
+`),
+	}, {
+		name: "cut stream",
+		logs: &godo.AppLogs{LiveURL: srv.URL + "/cut"},
+		expectedLogs: []byte(`::group::build logs
+step 1
+the live build logs ended early, the complete logs are printed once the deployment finished
+::endgroup::
+`),
+	}, {
+		name: "empty stream",
+		logs: &godo.AppLogs{LiveURL: srv.URL + "/empty"},
+	}, {
+		name: "unsupported live URL",
+		logs: &godo.AppLogs{LiveURL: "wss://example.com/logs"},
+	}, {
+		name: "no live URL",
+		logs: &godo.AppLogs{HistoricURLs: []string{"http://build.com"}},
+	}, {
+		name:    "fails to get logs",
+		logs:    &godo.AppLogs{},
+		logsErr: errors.New("an error"),
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			as := &mockedAppsService{}
+			as.On("GetLogs", ctx, appID, deploymentID, "", godo.AppLogTypeBuild, true, -1).Return(test.logs, &godo.Response{}, test.logsErr)
+
+			var actionLogs bytes.Buffer
+			d := &deployer{
+				action:     gha.New(gha.WithWriter(&actionLogs)),
+				apps:       as,
+				httpClient: srv.Client(),
+			}
+			streamed := d.streamLiveLogs(ctx, appID, deploymentID, godo.DeploymentPhase_Building, godo.AppLogTypeBuild)
+			require.Equal(t, test.streamed, streamed)
+			require.Equal(t, test.expectedLogs, actionLogs.Bytes())
+		})
+	}
+}
+
+func TestWaitForDeploymentTerminalStreamsLogs(t *testing.T) {
+	ctx := context.Background()
+	appID := "app-id"
+	deploymentID := "deployment-id"
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("building...\n"))
+	}))
+	defer srv.Close()
+
+	as := &mockedAppsService{}
+	as.On("GetDeployment", ctx, appID, deploymentID).Return(&godo.Deployment{
+		Phase: godo.DeploymentPhase_Building,
+	}, &godo.Response{}, nil).Once()
+	as.On("GetDeployment", ctx, appID, deploymentID).Return(&godo.Deployment{
+		Phase: godo.DeploymentPhase_Active,
+	}, &godo.Response{}, nil).Once()
+	as.On("GetLogs", ctx, appID, deploymentID, "", godo.AppLogTypeBuild, true, -1).Return(&godo.AppLogs{
+		LiveURL: srv.URL,
+	}, &godo.Response{}, nil).Once()
+
+	var actionLogs bytes.Buffer
+	d := &deployer{
+		action:     gha.New(gha.WithWriter(&actionLogs)),
+		apps:       as,
+		httpClient: srv.Client(),
+		inputs:     inputs{printBuildLogs: true},
+	}
+	dep, err := d.waitForDeploymentTerminal(ctx, appID, deploymentID)
+	require.NoError(t, err)
+	require.Equal(t, godo.DeploymentPhase_Active, dep.GetPhase())
+	require.Equal(t, []byte(`deployment is in phase: BUILDING
+::group::build logs
+building...
+::endgroup::
+deployment is in phase: ACTIVE
+`), actionLogs.Bytes())
+	require.True(t, d.streamedLogs[godo.AppLogTypeBuild])
+
+	as.AssertExpectations(t)
+}
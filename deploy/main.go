@@ -3,22 +3,38 @@ package main
 import (
 	"bytes"
 	"context"
-	"encoding/json"
+	_ "embed"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
 	"time"
 
 	"github.com/digitalocean/app_action/utils"
 	"github.com/digitalocean/godo"
-	gha "github.com/sethvargo/go-githubactions"
 	"sigs.k8s.io/yaml"
 )
 
+// actionYAML is the action's metadata. It defines the inputs when running as a CLI.
+//
+//go:embed action.yml
+var actionYAML []byte
+
 func main() {
-	ctx := context.Background()
-	a := gha.New()
+	// Cancel the context when the workflow run is canceled, which sends SIGINT
+	// and SIGTERM to the action before killing it.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	a, finish, err := utils.NewRuntime(actionYAML)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	defer finish()
 
 	in, err := getInputs(a)
 	if err != nil {
@@ -27,54 +43,137 @@ func main() {
 	// Mask the DO token to avoid accidentally leaking it.
 	a.AddMask(in.token)
 
-	do := godo.NewFromToken(in.token)
-	do.UserAgent = "do-app-action-deploy"
+	clientConfig := utils.ClientConfig{
+		BaseURL:  in.apiBaseURL,
+		ProxyURL: in.proxyURL,
+		CABundle: in.caBundle,
+	}
+	do, err := utils.NewClient(in.token, "do-app-action-deploy", clientConfig)
+	if err != nil {
+		a.Fatalf("failed to create API client: %v", err)
+	}
+	// Logs and health checks are fetched through the same proxy, trusting the same CAs.
+	httpClient, err := utils.NewHTTPClient(clientConfig)
+	if err != nil {
+		a.Fatalf("failed to create HTTP client: %v", err)
+	}
+	apps := utils.NewRetryingAppsService(do.Apps, utils.RetryConfig{
+		MaxRetries: in.apiMaxRetries,
+		MaxBackoff: in.apiMaxBackoff,
+	})
 	d := &deployer{
-		action:     a,
-		apps:       do.Apps,
-		httpClient: http.DefaultClient,
-		inputs:     in,
+		action:      a,
+		apps:        apps,
+		deployments: utils.NewDeploymentsService(do),
+		httpClient:  httpClient,
+		inputs:      in,
 	}
 
 	spec, err := d.createSpec(ctx)
 	if err != nil {
 		a.Fatalf("failed to create spec: %v", err)
 	}
+	// Mask secrets that were expanded from the environment to avoid leaking them.
+	maskSecrets(a, spec)
 
-	if in.deployPRPreview {
-		ghCtx, err := a.Context()
+	if in.deployPRPreview || in.skipIfUnchanged {
+		ciCtx, err := utils.NewCIContext(a, os.Getenv)
 		if err != nil {
-			a.Fatalf("failed to get GitHub context: %v", err)
+			a.Fatalf("failed to get CI context: %v", err)
 		}
+		d.ciContext = ciCtx
+	}
 
+	if in.deployPRPreview {
 		// If this is a PR preview, we need to sanitize the spec.
 		// Pass preservePRDomains flag to optionally keep custom domains.
-		if err := utils.SanitizeSpecForPullRequestPreview(spec, ghCtx, in.preservePRDomains); err != nil {
+		previewConfig := utils.PreviewConfig{
+			PreserveDomains: in.preservePRDomains,
+			NameTemplate:    in.previewNameTemplate,
+			AllowForks:      in.forkPRPreviews == forkPolicyAllow,
+			Resources:       in.previewResources,
+		}
+		if in.previewResources != nil && in.previewResources.InstanceSizeSlug != "" {
+			// Needed to only ever downgrade the instance sizes of components.
+			previewConfig.InstanceSizes, _, err = d.apps.ListInstanceSizes(ctx)
+			if err != nil {
+				a.Fatalf("failed to list instance sizes: %v", err)
+			}
+		}
+		if err := utils.SanitizeSpecForPullRequestPreview(spec, d.ciContext, previewConfig); err != nil {
 			a.Fatalf("failed to sanitize spec for PR preview: %v", err)
 		}
 	}
 
-	app, err := d.deploy(ctx, spec)
-	if app != nil {
+	if in.dryRun {
+		if _, err := d.plan(ctx, spec); err != nil {
+			a.Fatalf("failed to plan deployment: %v", err)
+		}
+		return
+	}
+
+	if in.deployTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, in.deployTimeout)
+		defer cancel()
+	}
+
+	res, err := d.deploy(ctx, spec)
+	if res.app != nil {
 		// Surface a JSON representation of the app regardless of success or failure.
-		appJSON, err := json.Marshal(app)
-		if err != nil {
-			a.Errorf("failed to marshal app: %v", err)
+		if err := setAppOutput(a, res.app, in.redactAllEnvs); err != nil {
+			a.Errorf("failed to set app output: %v", err)
 		}
-		a.SetOutput("app", string(appJSON))
+	}
+	setOutputs(a, res)
+	if in.jobSummary {
+		d.writeSummary(spec, res, err)
+	}
+	a.SetOutput("skipped", strconv.FormatBool(res.skipped))
+	a.SetOutput("superseded", strconv.FormatBool(errors.Is(err, errSuperseded)))
+	if errors.Is(err, errSuperseded) && !in.failOnSuperseded {
+		// Being superseded is not a failure of this deployment, but its changes
+		// are not necessarily live either.
+		a.Warningf("%v", err)
+		return
 	}
 	if err != nil {
+		if errors.Is(ctx.Err(), context.Canceled) {
+			a.Fatalf("deployment was aborted: %v", err)
+		}
 		a.Fatalf("failed to deploy: %v", err)
 	}
-	a.Infof("App is now live under URL: %s", app.GetLiveURL())
+	if liveURL := res.app.GetLiveURL(); liveURL != "" {
+		a.Infof("App is now live under URL: %s", liveURL)
+	}
 }
 
+const (
+	// pollInterval is the interval in which the deployment and app state is polled.
+	pollInterval = 2 * time.Second
+	// cancelTimeout bounds the request canceling a deployment, which usually
+	// happens after the original context is already done.
+	cancelTimeout = 30 * time.Second
+	// rollbackTimeout bounds rolling back a failed deployment, which usually
+	// happens after the deployment used up most of its deadline.
+	rollbackTimeout = 15 * time.Minute
+)
+
 // deployer is responsible for deploying the app.
 type deployer struct {
-	action     *gha.Action
-	apps       godo.AppsService
-	httpClient *http.Client
-	inputs     inputs
+	action      utils.Runtime
+	apps        godo.AppsService
+	deployments utils.DeploymentsService
+	httpClient  *http.Client
+	inputs      inputs
+
+	// ciContext describes the CI run the action runs in. It's only set for
+	// PR previews and if deployments are skipped if unchanged.
+	ciContext *utils.CIContext
+
+	// streamedLogs tracks the types of logs that were streamed live completely
+	// already.
+	streamedLogs map[godo.AppLogType]bool
 }
 
 func (d *deployer) createSpec(ctx context.Context) (*godo.AppSpec, error) {
@@ -106,51 +205,120 @@ func (d *deployer) createSpec(ctx context.Context) (*godo.AppSpec, error) {
 	return spec, nil
 }
 
+// result is the result of deploying an app.
+type result struct {
+	// app is the latest known state of the app. It is nil if the deployment
+	// failed before the app was found or created.
+	app *godo.App
+	// created is whether or not the app was newly created by the deployment.
+	created bool
+	// skipped is whether or not the deployment was skipped as nothing changed.
+	skipped bool
+	// deployment is the latest known state of the deployment, usually its
+	// terminal state. It is nil if the deployment failed before it was started.
+	deployment *godo.Deployment
+	// buildLogs are the build logs of the deployment.
+	buildLogs []byte
+	// deployLogs are the deploy logs of the deployment.
+	deployLogs []byte
+}
+
 // deploy deploys the app and waits for it to be live.
-func (d *deployer) deploy(ctx context.Context, spec *godo.AppSpec) (*godo.App, error) {
-	// Either create or update the app.
+// The returned result is never nil and contains as much information as was
+// gathered before a potential failure.
+func (d *deployer) deploy(ctx context.Context, spec *godo.AppSpec) (*result, error) {
+	res := &result{}
+
+	// Either create, update or redeploy the app.
 	app, err := utils.FindAppByName(ctx, d.apps, spec.GetName())
 	if err != nil {
-		return nil, fmt.Errorf("failed to get app: %w", err)
+		return res, fmt.Errorf("failed to get app: %w", err)
+	}
+	// Redeploying doesn't touch the spec, so there's nothing to compare or validate.
+	redeploy := d.inputs.mode == deployModeRedeploy
+	if app != nil && d.inputs.skipIfUnchanged && !redeploy {
+		reason, err := d.changeReason(app, spec)
+		if err != nil {
+			return res, fmt.Errorf("failed to compare app: %w", err)
+		}
+		if reason == "" {
+			d.action.Infof("app %q is unchanged, skipping deployment", spec.Name)
+			res.app = app
+			res.deployment = app.GetActiveDeployment()
+			res.skipped = true
+			return res, nil
+		}
+		d.action.Infof("app %q changed, deploying: %s", spec.Name, reason)
+	}
+	if d.inputs.validateSpec && !redeploy {
+		if err := d.validateSpec(ctx, spec, app); err != nil {
+			return res, err
+		}
 	}
-	if app == nil {
+	switch {
+	case redeploy:
+		if app == nil {
+			return res, fmt.Errorf("app %q does not exist and can't be redeployed", spec.Name)
+		}
+		if err := d.handleInProgressDeployment(ctx, app); err != nil {
+			return res, err
+		}
+		d.action.Infof("app %q already exists, redeploying...", spec.Name)
+		res.deployment, _, err = d.apps.CreateDeployment(ctx, app.GetID(), &godo.DeploymentCreateRequest{ForceBuild: d.inputs.forceBuild})
+		if err != nil {
+			return res, fmt.Errorf("failed to create deployment: %w", err)
+		}
+	case app == nil:
 		d.action.Infof("app %q does not exist yet, creating...", spec.Name)
 		app, _, err = d.apps.Create(ctx, &godo.AppCreateRequest{Spec: spec, ProjectID: d.inputs.projectID})
 		if err != nil {
-			return nil, fmt.Errorf("failed to create app: %w", err)
+			return res, fmt.Errorf("failed to create app: %w", err)
+		}
+		res.created = true
+	default:
+		if err := d.handleInProgressDeployment(ctx, app); err != nil {
+			return res, err
 		}
-	} else {
 		d.action.Infof("app %q already exists, updating...", spec.Name)
 		app, _, err = d.apps.Update(ctx, app.GetID(), &godo.AppUpdateRequest{Spec: spec, UpdateAllSourceVersions: true})
 		if err != nil {
-			return nil, fmt.Errorf("failed to update app: %w", err)
+			return res, fmt.Errorf("failed to update app: %w", err)
 		}
 	}
+	res.app = app
 
-	ds, _, err := d.apps.ListDeployments(ctx, app.GetID(), &godo.ListOptions{PerPage: 1})
-	if err != nil {
-		return nil, fmt.Errorf("failed to list deployments: %w", err)
-	}
-	if len(ds) == 0 {
-		return nil, fmt.Errorf("expected a deployment right after creating/updating the app, but got none")
+	if res.deployment == nil {
+		ds, _, err := d.apps.ListDeployments(ctx, app.GetID(), &godo.ListOptions{PerPage: 1})
+		if err != nil {
+			return res, fmt.Errorf("failed to list deployments: %w", err)
+		}
+		if len(ds) == 0 {
+			return res, fmt.Errorf("expected a deployment right after creating/updating the app, but got none")
+		}
+		// The latest deployment is the deployment we just created.
+		res.deployment = ds[0]
 	}
-	// The latest deployment is the deployment we just created.
-	deploymentID := ds[0].GetID()
+	deploymentID := res.deployment.GetID()
 
 	d.action.Infof("wait for deployment to finish")
 	dep, err := d.waitForDeploymentTerminal(ctx, app.ID, deploymentID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to wait deployment to finish: %w", err)
+		return res, fmt.Errorf("failed to wait deployment to finish: %w", err)
+	}
+	res.deployment = dep
+	if err := d.setStepsOutput(dep); err != nil {
+		return res, err
 	}
 
 	buildLogs, err := d.getLogs(ctx, app.ID, deploymentID, godo.AppLogTypeBuild)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get build logs: %w", err)
+		return res, fmt.Errorf("failed to get build logs: %w", err)
 	}
+	res.buildLogs = buildLogs
 	if len(buildLogs) > 0 {
 		d.action.SetOutput("build_logs", string(buildLogs))
 
-		if d.inputs.printBuildLogs {
+		if d.inputs.printBuildLogs && !d.streamedLogs[godo.AppLogTypeBuild] {
 			d.action.Group("build logs")
 			d.action.Infof(string(buildLogs))
 			d.action.EndGroup()
@@ -159,66 +327,192 @@ func (d *deployer) deploy(ctx context.Context, spec *godo.AppSpec) (*godo.App, e
 
 	deployLogs, err := d.getLogs(ctx, app.ID, deploymentID, godo.AppLogTypeDeploy)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get deploy logs: %w", err)
+		return res, fmt.Errorf("failed to get deploy logs: %w", err)
 	}
+	res.deployLogs = deployLogs
 	if len(deployLogs) > 0 {
 		d.action.SetOutput("deploy_logs", string(deployLogs))
 
-		if d.inputs.printDeployLogs {
+		if d.inputs.printDeployLogs && !d.streamedLogs[godo.AppLogTypeDeploy] {
 			d.action.Group("deploy logs")
 			d.action.Infof(string(deployLogs))
 			d.action.EndGroup()
 		}
 	}
 
+	if dep.Phase == godo.DeploymentPhase_Superseded {
+		// Another deployment took over, so there's nothing to roll back or verify.
+		app, _, err := d.apps.Get(ctx, app.ID)
+		if err != nil {
+			return res, fmt.Errorf("failed to get app after it was superseded: %w", err)
+		}
+		res.app = app
+		return res, fmt.Errorf("deployment %s was %w", deploymentID, errSuperseded)
+	}
+
 	if dep.Phase != godo.DeploymentPhase_Active {
+		deployErr := fmt.Errorf("deployment failed in phase %q", dep.Phase)
+		if reason := failedStepReason(dep); reason != "" {
+			deployErr = fmt.Errorf("deployment failed in phase %q, %s", dep.Phase, reason)
+		}
+		if d.inputs.rollbackOnFailure {
+			if err := d.rollback(ctx, app.ID, deploymentID); err != nil {
+				deployErr = fmt.Errorf("%w, rollback failed: %w", deployErr, err)
+			}
+		}
+
 		// Fetch the app to get the latest state before returning.
 		app, _, err := d.apps.Get(ctx, app.ID)
 		if err != nil {
-			return nil, fmt.Errorf("failed to get app after it failed: %w", err)
+			return res, fmt.Errorf("failed to get app after it failed: %w", err)
+		}
+		res.app = app
+		return res, deployErr
+	}
+
+	if !hasRoutableComponents(spec) {
+		// Apps without routable components never get a live URL, so there's nothing to wait for.
+		d.action.Infof("app has no routable components, not waiting for a live URL")
+		if len(d.inputs.healthChecks) > 0 {
+			d.action.Warningf("skipping health checks as the app has no routable components")
+		}
+		app, _, err := d.apps.Get(ctx, app.ID)
+		if err != nil {
+			return res, fmt.Errorf("failed to get app: %w", err)
 		}
-		return app, fmt.Errorf("deployment failed in phase %q", dep.Phase)
+		res.app = app
+		if err := d.reportComponentStatuses(spec, dep); err != nil {
+			return res, err
+		}
+		return res, nil
 	}
 
 	app, err = d.waitForAppLiveURL(ctx, app.ID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to wait for app to have a live URL: %w", err)
+		return res, fmt.Errorf("failed to wait for app to have a live URL: %w", err)
 	}
 
-	return app, nil
+	if len(d.inputs.healthChecks) > 0 {
+		if err := d.verifyHealth(ctx, app.GetLiveURL()); err != nil {
+			healthErr := fmt.Errorf("app is unhealthy: %w", err)
+			if d.inputs.rollbackOnFailure {
+				if err := d.rollback(ctx, app.ID, deploymentID); err != nil {
+					healthErr = fmt.Errorf("%w, rollback failed: %w", healthErr, err)
+				}
+			}
+			res.app = app
+			return res, healthErr
+		}
+	}
+
+	res.app = app
+	return res, nil
 }
 
 // waitForDeploymentTerminal waits for the given deployment to be in a terminal state.
+// If the context's deadline is hit or a phase takes longer than its configured timeout,
+// the deployment is canceled.
 func (d *deployer) waitForDeploymentTerminal(ctx context.Context, appID, deploymentID string) (*godo.Deployment, error) {
-	t := time.NewTicker(2 * time.Second)
+	t := time.NewTicker(pollInterval)
 	defer t.Stop()
 
 	var dep *godo.Deployment
 	var currentPhase godo.DeploymentPhase
+	var phaseStarted time.Time
+	stepStatuses := make(map[string]godo.DeploymentProgressStepStatus)
 	for {
 		var err error
 		dep, _, err = d.apps.GetDeployment(ctx, appID, deploymentID)
 		if err != nil {
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return nil, d.cancelTimedOutDeployment(ctx, appID, deploymentID, currentPhase)
+			}
+			if errors.Is(ctx.Err(), context.Canceled) {
+				return nil, d.abortDeployment(ctx, appID, deploymentID, currentPhase)
+			}
 			return nil, fmt.Errorf("failed to get deployment: %w", err)
 		}
 
-		if currentPhase != dep.GetPhase() {
+		phaseChanged := currentPhase != dep.GetPhase()
+		if phaseChanged {
 			d.action.Infof("deployment is in phase: %s", dep.GetPhase())
 			currentPhase = dep.GetPhase()
+			phaseStarted = time.Now()
+		}
+		d.logStepTransitions(stepStatuses, dep)
+
+		if logType, ok := d.liveLogType(currentPhase); phaseChanged && ok && !d.streamedLogs[logType] {
+			if d.streamedLogs == nil {
+				d.streamedLogs = make(map[godo.AppLogType]bool)
+			}
+			d.streamedLogs[logType] = d.streamLiveLogs(ctx, appID, deploymentID, currentPhase, logType)
+			// Skip waiting as the stream likely took a while.
+			continue
 		}
 
 		if isInTerminalPhase(dep) {
 			return dep, nil
 		}
 
+		if timeout := d.phaseTimeout(currentPhase); timeout > 0 && time.Since(phaseStarted) > timeout {
+			d.cancelDeployment(ctx, appID, deploymentID)
+			return nil, fmt.Errorf("deployment exceeded the timeout of %s in phase %q", timeout, currentPhase)
+		}
+
 		select {
 		case <-ctx.Done():
-			return nil, ctx.Err()
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return nil, d.cancelTimedOutDeployment(ctx, appID, deploymentID, currentPhase)
+			}
+			return nil, d.abortDeployment(ctx, appID, deploymentID, currentPhase)
 		case <-t.C:
 		}
 	}
 }
 
+// phaseTimeout returns the configured timeout for the given phase or zero if
+// the phase is not limited.
+func (d *deployer) phaseTimeout(phase godo.DeploymentPhase) time.Duration {
+	switch phase {
+	case godo.DeploymentPhase_Building:
+		return d.inputs.buildPhaseTimeout
+	case godo.DeploymentPhase_Deploying:
+		return d.inputs.deployPhaseTimeout
+	}
+	return 0
+}
+
+// cancelTimedOutDeployment cancels a deployment that hit the overall deadline
+// and returns an error naming the phase it was stuck in.
+func (d *deployer) cancelTimedOutDeployment(ctx context.Context, appID, deploymentID string, phase godo.DeploymentPhase) error {
+	d.cancelDeployment(ctx, appID, deploymentID)
+	return fmt.Errorf("deployment timed out after %s in phase %q", d.inputs.deployTimeout, phase)
+}
+
+// abortDeployment handles the wait for a deployment being aborted, usually
+// because the workflow run was canceled. The deployment is canceled only if
+// configured, otherwise it's left running.
+func (d *deployer) abortDeployment(ctx context.Context, appID, deploymentID string, phase godo.DeploymentPhase) error {
+	if d.inputs.cancelOnAbort {
+		d.cancelDeployment(ctx, appID, deploymentID)
+		return fmt.Errorf("aborted while deployment %s was in phase %q, the deployment was canceled", deploymentID, phase)
+	}
+	return fmt.Errorf("aborted while deployment %s was in phase %q, the deployment keeps running in the background", deploymentID, phase)
+}
+
+// cancelDeployment cancels the given deployment on a best-effort basis.
+// The passed context is likely done already, so the cancellation uses a
+// detached context of its own.
+func (d *deployer) cancelDeployment(ctx context.Context, appID, deploymentID string) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), cancelTimeout)
+	defer cancel()
+
+	d.action.Infof("canceling deployment %s", deploymentID)
+	if _, _, err := d.deployments.CancelDeployment(ctx, appID, deploymentID); err != nil {
+		d.action.Warningf("failed to cancel deployment %s: %v", deploymentID, err)
+	}
+}
+
 // isInTerminalPhase returns whether or not the given deployment is in a terminal phase.
 func isInTerminalPhase(d *godo.Deployment) bool {
 	switch d.GetPhase() {
@@ -230,7 +524,7 @@ func isInTerminalPhase(d *godo.Deployment) bool {
 
 // waitForAppLiveURL waits for the given app to have a non-empty live URL.
 func (d *deployer) waitForAppLiveURL(ctx context.Context, appID string) (*godo.App, error) {
-	t := time.NewTicker(2 * time.Second)
+	t := time.NewTicker(pollInterval)
 	defer t.Stop()
 
 	var a *godo.App
@@ -258,7 +552,7 @@ func (d *deployer) getLogs(ctx context.Context, appID, deploymentID string, logT
 	logsResp, resp, err := d.apps.GetLogs(ctx, appID, deploymentID, "", logType, true, -1)
 	if err != nil {
 		// Ignore if we get a 400, as this means the respective state was never reached or skipped.
-		if resp.StatusCode == http.StatusBadRequest {
+		if resp != nil && resp.StatusCode == http.StatusBadRequest {
 			return nil, nil
 		}
 
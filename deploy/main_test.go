@@ -8,7 +8,9 @@ import (
 	"net/http"
 	"os"
 	"testing"
+	"time"
 
+	"github.com/digitalocean/app_action/utils"
 	"github.com/digitalocean/godo"
 	gha "github.com/sethvargo/go-githubactions"
 	"github.com/stretchr/testify/mock"
@@ -184,11 +186,13 @@ func TestDeploy(t *testing.T) {
 	appID := "app-id"
 	deploymentID := "deployment-id"
 	spec := &godo.AppSpec{
-		Name: "foo",
+		Name:     "foo",
+		Services: []*godo.AppServiceSpec{{Name: "web"}},
 	}
 
 	tests := []struct {
 		name           string
+		spec           *godo.AppSpec
 		appService     *mockedAppsService
 		logsRT         *mockedRoundtripper
 		inputs         inputs
@@ -288,6 +292,107 @@ _GitHubActionsFileCommandDelimeter_
 deploy_logs<<_GitHubActionsFileCommandDelimeter_
 deploy log
 _GitHubActionsFileCommandDelimeter_
+`),
+	}, {
+		name: "success without routable components",
+		spec: &godo.AppSpec{
+			Name:    "foo",
+			Workers: []*godo.AppWorkerSpec{{Name: "worker"}},
+		},
+		appService: func() *mockedAppsService {
+			as := &mockedAppsService{}
+			as.On("List", ctx, mock.Anything).Return([]*godo.App{}, &godo.Response{}, nil)
+			as.On("Create", ctx, mock.Anything).Return(&godo.App{ID: appID}, &godo.Response{}, nil)
+			as.On("ListDeployments", ctx, appID, mock.Anything).Return([]*godo.Deployment{{
+				ID: deploymentID,
+			}}, &godo.Response{}, nil)
+			as.On("GetDeployment", ctx, appID, deploymentID).Return(&godo.Deployment{
+				Phase: godo.DeploymentPhase_Active,
+				Progress: &godo.DeploymentProgress{
+					Steps: []*godo.DeploymentProgressStep{{
+						Name:          "worker",
+						ComponentName: "worker",
+						Status:        godo.DeploymentProgressStepStatus_Success,
+					}},
+				},
+			}, &godo.Response{}, nil)
+			as.On("GetLogs", ctx, appID, deploymentID, "", godo.AppLogTypeBuild, true, -1).Return(&godo.AppLogs{}, &godo.Response{}, nil)
+			as.On("GetLogs", ctx, appID, deploymentID, "", godo.AppLogTypeDeploy, true, -1).Return(&godo.AppLogs{}, &godo.Response{}, nil)
+			// The app never gets a live URL.
+			as.On("Get", ctx, appID).Return(&godo.App{ID: appID}, &godo.Response{}, nil).Once()
+			return as
+		}(),
+		logsRT: &mockedRoundtripper{},
+		expectedLogs: []byte(`app "foo" does not exist yet, creating...
+wait for deployment to finish
+deployment is in phase: ACTIVE
+step worker is SUCCESS
+app has no routable components, not waiting for a live URL
+component worker is SUCCESS
+`),
+		expectedOutput: []byte(`deployment_steps<<_GitHubActionsFileCommandDelimeter_
+[{"name":"worker","component":"worker","status":"SUCCESS"}]
+_GitHubActionsFileCommandDelimeter_
+component_statuses<<_GitHubActionsFileCommandDelimeter_
+{"worker":"SUCCESS"}
+_GitHubActionsFileCommandDelimeter_
+`),
+	}, {
+		name: "redeploy",
+		appService: func() *mockedAppsService {
+			as := &mockedAppsService{}
+			as.On("List", ctx, mock.Anything).Return([]*godo.App{{ID: appID, Spec: spec}}, &godo.Response{}, nil)
+			as.On("CreateDeployment", ctx, appID, []*godo.DeploymentCreateRequest{{ForceBuild: true}}).Return(&godo.Deployment{
+				ID: deploymentID,
+			}, &godo.Response{}, nil)
+			as.On("GetDeployment", ctx, appID, deploymentID).Return(&godo.Deployment{
+				Phase: godo.DeploymentPhase_Active,
+			}, &godo.Response{}, nil)
+			as.On("GetLogs", ctx, appID, deploymentID, "", godo.AppLogTypeBuild, true, -1).Return(&godo.AppLogs{}, &godo.Response{}, nil)
+			as.On("GetLogs", ctx, appID, deploymentID, "", godo.AppLogTypeDeploy, true, -1).Return(&godo.AppLogs{}, &godo.Response{}, nil)
+			as.On("Get", ctx, appID).Return(&godo.App{ID: appID, LiveURL: "https://example.com"}, &godo.Response{}, nil)
+			return as
+		}(),
+		logsRT: &mockedRoundtripper{},
+		// The spec is neither validated nor updated.
+		inputs: inputs{mode: deployModeRedeploy, forceBuild: true, validateSpec: true},
+		expectedLogs: []byte(`app "foo" already exists, redeploying...
+wait for deployment to finish
+deployment is in phase: ACTIVE
+`),
+	}, {
+		name: "redeploy of missing app",
+		appService: func() *mockedAppsService {
+			as := &mockedAppsService{}
+			as.On("List", ctx, mock.Anything).Return([]*godo.App{}, &godo.Response{}, nil)
+			return as
+		}(),
+		inputs: inputs{mode: deployModeRedeploy},
+		err:    true,
+	}, {
+		name: "superseded",
+		appService: func() *mockedAppsService {
+			as := &mockedAppsService{}
+			as.On("List", ctx, mock.Anything).Return([]*godo.App{{ID: appID, Spec: spec}}, &godo.Response{}, nil)
+			as.On("Update", ctx, appID, mock.Anything).Return(&godo.App{ID: appID}, &godo.Response{}, nil)
+			as.On("ListDeployments", ctx, appID, mock.Anything).Return([]*godo.Deployment{{
+				ID: deploymentID,
+			}}, &godo.Response{}, nil)
+			as.On("GetDeployment", ctx, appID, deploymentID).Return(&godo.Deployment{
+				Phase: godo.DeploymentPhase_Superseded,
+			}, &godo.Response{}, nil)
+			as.On("GetLogs", ctx, appID, deploymentID, "", godo.AppLogTypeBuild, true, -1).Return(&godo.AppLogs{}, &godo.Response{}, nil)
+			as.On("GetLogs", ctx, appID, deploymentID, "", godo.AppLogTypeDeploy, true, -1).Return(&godo.AppLogs{}, &godo.Response{}, nil)
+			as.On("Get", ctx, appID).Return(&godo.App{ID: appID}, &godo.Response{}, nil)
+			return as
+		}(),
+		logsRT: &mockedRoundtripper{},
+		// Superseded deployments must not be rolled back.
+		inputs: inputs{rollbackOnFailure: true},
+		err:    true,
+		expectedLogs: []byte(`app "foo" already exists, updating...
+wait for deployment to finish
+deployment is in phase: SUPERSEDED
 `),
 	}, {
 		name: "fails to deploy",
@@ -340,6 +445,19 @@ _GitHubActionsFileCommandDelimeter_
 			return as
 		}(),
 		err: true,
+	}, {
+		name: "fails spec validation",
+		appService: func() *mockedAppsService {
+			as := &mockedAppsService{}
+			as.On("List", ctx, mock.Anything).Return([]*godo.App{}, &godo.Response{}, nil)
+			as.On("Propose", ctx, mock.Anything).Return(&godo.AppProposeResponse{}, &godo.Response{}, &godo.ErrorResponse{
+				Response: &http.Response{StatusCode: http.StatusBadRequest},
+				Message:  "invalid spec",
+			})
+			return as
+		}(),
+		inputs: inputs{validateSpec: true},
+		err:    true,
 	}, {
 		name: "fails to create app",
 		appService: func() *mockedAppsService {
@@ -483,7 +601,11 @@ deployment is in phase: ACTIVE
 				httpClient: &http.Client{Transport: test.logsRT},
 				inputs:     test.inputs,
 			}
-			_, err := d.deploy(ctx, spec)
+			testSpec := spec
+			if test.spec != nil {
+				testSpec = test.spec
+			}
+			_, err := d.deploy(ctx, testSpec)
 			if err != nil && !test.err {
 				t.Fatalf("unexpected error: %v", err)
 			}
@@ -506,6 +628,129 @@ deployment is in phase: ACTIVE
 	}
 }
 
+func TestWaitForDeploymentTerminalTimeouts(t *testing.T) {
+	appID := "app-id"
+	deploymentID := "deployment-id"
+
+	tests := []struct {
+		name         string
+		inputs       inputs
+		ctxTimeout   time.Duration
+		expectedErr  string
+		expectedLogs []byte
+	}{{
+		name:        "overall deadline",
+		inputs:      inputs{deployTimeout: 50 * time.Millisecond},
+		ctxTimeout:  50 * time.Millisecond,
+		expectedErr: `deployment timed out after 50ms in phase "BUILDING"`,
+		expectedLogs: []byte(`deployment is in phase: BUILDING
+canceling deployment deployment-id
+`),
+	}, {
+		name:        "phase timeout",
+		inputs:      inputs{buildPhaseTimeout: time.Nanosecond},
+		ctxTimeout:  time.Minute,
+		expectedErr: `deployment exceeded the timeout of 1ns in phase "BUILDING"`,
+		expectedLogs: []byte(`deployment is in phase: BUILDING
+canceling deployment deployment-id
+`),
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), test.ctxTimeout)
+			defer cancel()
+
+			as := &mockedAppsService{}
+			as.On("GetDeployment", mock.Anything, appID, deploymentID).Return(&godo.Deployment{
+				Phase: godo.DeploymentPhase_Building,
+			}, &godo.Response{}, nil)
+			ds := &mockedDeploymentsService{}
+			ds.On("CancelDeployment", mock.Anything, appID, deploymentID).Return(&godo.Deployment{
+				Phase: godo.DeploymentPhase_Canceled,
+			}, &godo.Response{}, nil).Once()
+
+			var actionLogs bytes.Buffer
+			d := &deployer{
+				action:      gha.New(gha.WithWriter(&actionLogs)),
+				apps:        as,
+				deployments: ds,
+				inputs:      test.inputs,
+			}
+			_, err := d.waitForDeploymentTerminal(ctx, appID, deploymentID)
+			require.EqualError(t, err, test.expectedErr)
+			require.Equal(t, test.expectedLogs, actionLogs.Bytes())
+
+			ds.AssertExpectations(t)
+		})
+	}
+}
+
+func TestWaitForDeploymentTerminalAbort(t *testing.T) {
+	appID := "app-id"
+	deploymentID := "deployment-id"
+
+	tests := []struct {
+		name         string
+		inputs       inputs
+		expectCancel bool
+		expectedErr  string
+		expectedLogs []byte
+	}{{
+		name:        "left running",
+		expectedErr: `aborted while deployment deployment-id was in phase "BUILDING", the deployment keeps running in the background`,
+		expectedLogs: []byte(`deployment is in phase: BUILDING
+`),
+	}, {
+		name:         "canceled",
+		inputs:       inputs{cancelOnAbort: true},
+		expectCancel: true,
+		expectedErr:  `aborted while deployment deployment-id was in phase "BUILDING", the deployment was canceled`,
+		expectedLogs: []byte(`deployment is in phase: BUILDING
+canceling deployment deployment-id
+`),
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			time.AfterFunc(50*time.Millisecond, cancel)
+
+			as := &mockedAppsService{}
+			as.On("GetDeployment", mock.Anything, appID, deploymentID).Return(&godo.Deployment{
+				Phase: godo.DeploymentPhase_Building,
+			}, &godo.Response{}, nil)
+			ds := &mockedDeploymentsService{}
+			if test.expectCancel {
+				ds.On("CancelDeployment", mock.Anything, appID, deploymentID).Return(&godo.Deployment{
+					Phase: godo.DeploymentPhase_Canceled,
+				}, &godo.Response{}, nil).Once()
+			}
+
+			var actionLogs bytes.Buffer
+			d := &deployer{
+				action:      gha.New(gha.WithWriter(&actionLogs)),
+				apps:        as,
+				deployments: ds,
+				inputs:      test.inputs,
+			}
+			_, err := d.waitForDeploymentTerminal(ctx, appID, deploymentID)
+			require.EqualError(t, err, test.expectedErr)
+			require.Equal(t, test.expectedLogs, actionLogs.Bytes())
+
+			ds.AssertExpectations(t)
+		})
+	}
+}
+
+func TestPhaseTimeout(t *testing.T) {
+	d := &deployer{inputs: inputs{buildPhaseTimeout: time.Minute, deployPhaseTimeout: time.Hour}}
+	require.Equal(t, time.Minute, d.phaseTimeout(godo.DeploymentPhase_Building))
+	require.Equal(t, time.Hour, d.phaseTimeout(godo.DeploymentPhase_Deploying))
+	require.Zero(t, d.phaseTimeout(godo.DeploymentPhase_PendingBuild))
+}
+
 type mockedRoundtripper struct {
 	mock.Mock
 }
@@ -540,6 +785,11 @@ func (m *mockedAppsService) List(ctx context.Context, opt *godo.ListOptions) ([]
 	return args.Get(0).([]*godo.App), args.Get(1).(*godo.Response), args.Error(2)
 }
 
+func (m *mockedAppsService) Propose(ctx context.Context, req *godo.AppProposeRequest) (*godo.AppProposeResponse, *godo.Response, error) {
+	args := m.Called(ctx, req)
+	return args.Get(0).(*godo.AppProposeResponse), args.Get(1).(*godo.Response), args.Error(2)
+}
+
 func (m *mockedAppsService) GetDeployment(ctx context.Context, appID string, deploymentID string) (*godo.Deployment, *godo.Response, error) {
 	args := m.Called(ctx, appID, deploymentID)
 	return args.Get(0).(*godo.Deployment), args.Get(1).(*godo.Response), args.Error(2)
@@ -550,7 +800,26 @@ func (m *mockedAppsService) ListDeployments(ctx context.Context, appID string, o
 	return args.Get(0).([]*godo.Deployment), args.Get(1).(*godo.Response), args.Error(2)
 }
 
+func (m *mockedAppsService) CreateDeployment(ctx context.Context, appID string, create ...*godo.DeploymentCreateRequest) (*godo.Deployment, *godo.Response, error) {
+	args := m.Called(ctx, appID, create)
+	return args.Get(0).(*godo.Deployment), args.Get(1).(*godo.Response), args.Error(2)
+}
+
 func (m *mockedAppsService) GetLogs(ctx context.Context, appID, deploymentID, component string, logType godo.AppLogType, follow bool, tailLines int) (*godo.AppLogs, *godo.Response, error) {
 	args := m.Called(ctx, appID, deploymentID, component, logType, follow, tailLines)
 	return args.Get(0).(*godo.AppLogs), args.Get(1).(*godo.Response), args.Error(2)
 }
+
+type mockedDeploymentsService struct {
+	mock.Mock
+}
+
+func (m *mockedDeploymentsService) CancelDeployment(ctx context.Context, appID, deploymentID string) (*godo.Deployment, *godo.Response, error) {
+	args := m.Called(ctx, appID, deploymentID)
+	return args.Get(0).(*godo.Deployment), args.Get(1).(*godo.Response), args.Error(2)
+}
+
+func (m *mockedDeploymentsService) Rollback(ctx context.Context, appID string, req *utils.AppRollbackRequest) (*godo.Deployment, *godo.Response, error) {
+	args := m.Called(ctx, appID, req)
+	return args.Get(0).(*godo.Deployment), args.Get(1).(*godo.Response), args.Error(2)
+}
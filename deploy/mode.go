@@ -0,0 +1,22 @@
+package main
+
+import "fmt"
+
+// deployMode defines how the app is deployed.
+type deployMode string
+
+const (
+	// deployModeUpdate creates the app or updates its spec, which triggers a deployment.
+	deployModeUpdate deployMode = "update"
+	// deployModeRedeploy creates a deployment of the existing app without changing its spec.
+	deployModeRedeploy deployMode = "redeploy"
+)
+
+// parseDeployMode parses the given deploy mode.
+func parseDeployMode(s string) (deployMode, error) {
+	switch m := deployMode(s); m {
+	case deployModeUpdate, deployModeRedeploy:
+		return m, nil
+	}
+	return "", fmt.Errorf("invalid mode %q, must be one of %q or %q", s, deployModeUpdate, deployModeRedeploy)
+}
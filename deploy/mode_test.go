@@ -0,0 +1,18 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseDeployMode(t *testing.T) {
+	for _, mode := range []string{"update", "redeploy"} {
+		got, err := parseDeployMode(mode)
+		require.NoError(t, err)
+		require.Equal(t, deployMode(mode), got)
+	}
+
+	_, err := parseDeployMode("")
+	require.EqualError(t, err, `invalid mode "", must be one of "update" or "redeploy"`)
+}
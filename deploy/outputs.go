@@ -0,0 +1,77 @@
+package main
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	"github.com/digitalocean/app_action/utils"
+	"github.com/digitalocean/godo"
+)
+
+// setOutputs surfaces the most commonly used metadata of the app and the
+// deployment as individual outputs, so they can be used without parsing the
+// "app" output.
+func setOutputs(a utils.Runtime, res *result) {
+	if res.app != nil {
+		a.SetOutput("app_id", res.app.GetID())
+		a.SetOutput("app_name", res.app.GetSpec().GetName())
+		a.SetOutput("live_url", res.app.GetLiveURL())
+		a.SetOutput("default_ingress", res.app.GetDefaultIngress())
+		a.SetOutput("created", strconv.FormatBool(res.created))
+
+		urls := componentURLs(res.app)
+		for _, name := range sortedKeys(urls, nil) {
+			a.SetOutput("component_url_"+name, urls[name])
+		}
+		if len(urls) > 0 {
+			urlsJSON, err := json.Marshal(urls)
+			if err != nil {
+				a.Errorf("failed to marshal component URLs: %v", err)
+			} else {
+				a.SetOutput("component_urls", string(urlsJSON))
+			}
+		}
+	}
+	if res.deployment != nil {
+		a.SetOutput("deployment_id", res.deployment.GetID())
+		a.SetOutput("deployment_phase", string(res.deployment.GetPhase()))
+	}
+}
+
+// componentURLs returns the public URLs of all components of the app that
+// are routable via its live URL, keyed by component name. Components that are
+// routed via multiple paths get the URL of the first one.
+func componentURLs(app *godo.App) map[string]string {
+	liveURL := strings.TrimRight(app.GetLiveURL(), "/")
+	if liveURL == "" {
+		return nil
+	}
+
+	urls := make(map[string]string)
+	addURL := func(component, path string) {
+		if _, ok := urls[component]; ok || component == "" {
+			return
+		}
+		urls[component] = liveURL + "/" + strings.TrimLeft(path, "/")
+	}
+
+	spec := app.GetSpec()
+	for _, rule := range spec.GetIngress().GetRules() {
+		addURL(rule.GetComponent().GetName(), rule.GetMatch().GetPath().GetPrefix())
+	}
+	// Older specs define the routes on the components themselves.
+	_ = spec.ForEachAppComponentSpec(func(c godo.AppComponentSpec) error {
+		if rc, ok := c.(godo.AppRoutableComponentSpec); ok {
+			for _, route := range rc.GetRoutes() {
+				addURL(c.GetName(), route.GetPath())
+			}
+		}
+		return nil
+	})
+
+	if len(urls) == 0 {
+		return nil
+	}
+	return urls
+}
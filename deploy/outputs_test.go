@@ -0,0 +1,129 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/digitalocean/godo"
+	gha "github.com/sethvargo/go-githubactions"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetOutputs(t *testing.T) {
+	var actionLogs bytes.Buffer
+	outputFilePath := t.TempDir() + "/output"
+	a := gha.New(gha.WithWriter(&actionLogs), gha.WithGetenv(func(k string) string {
+		if k == "GITHUB_OUTPUT" {
+			return outputFilePath
+		}
+		return ""
+	}))
+
+	setOutputs(a, &result{
+		app: &godo.App{
+			ID:             "app-id",
+			LiveURL:        "https://example.com",
+			DefaultIngress: "https://foo.ondigitalocean.app",
+			Spec: &godo.AppSpec{
+				Name: "foo",
+				Services: []*godo.AppServiceSpec{{
+					Name:   "api",
+					Routes: []*godo.AppRouteSpec{{Path: "/api"}},
+				}},
+			},
+		},
+		created: true,
+		deployment: &godo.Deployment{
+			ID:    "deployment-id",
+			Phase: godo.DeploymentPhase_Active,
+		},
+	})
+
+	output, err := os.ReadFile(outputFilePath)
+	require.NoError(t, err)
+	require.Equal(t, `app_id<<_GitHubActionsFileCommandDelimeter_
+app-id
+_GitHubActionsFileCommandDelimeter_
+app_name<<_GitHubActionsFileCommandDelimeter_
+foo
+_GitHubActionsFileCommandDelimeter_
+live_url<<_GitHubActionsFileCommandDelimeter_
+https://example.com
+_GitHubActionsFileCommandDelimeter_
+default_ingress<<_GitHubActionsFileCommandDelimeter_
+https://foo.ondigitalocean.app
+_GitHubActionsFileCommandDelimeter_
+created<<_GitHubActionsFileCommandDelimeter_
+true
+_GitHubActionsFileCommandDelimeter_
+component_url_api<<_GitHubActionsFileCommandDelimeter_
+https://example.com/api
+_GitHubActionsFileCommandDelimeter_
+component_urls<<_GitHubActionsFileCommandDelimeter_
+{"api":"https://example.com/api"}
+_GitHubActionsFileCommandDelimeter_
+deployment_id<<_GitHubActionsFileCommandDelimeter_
+deployment-id
+_GitHubActionsFileCommandDelimeter_
+deployment_phase<<_GitHubActionsFileCommandDelimeter_
+ACTIVE
+_GitHubActionsFileCommandDelimeter_
+`, string(output))
+	require.Empty(t, actionLogs.String())
+}
+
+func TestComponentURLs(t *testing.T) {
+	tests := []struct {
+		name     string
+		app      *godo.App
+		expected map[string]string
+	}{{
+		name: "no live URL",
+		app: &godo.App{Spec: &godo.AppSpec{
+			Services: []*godo.AppServiceSpec{{Name: "web", Routes: []*godo.AppRouteSpec{{Path: "/"}}}},
+		}},
+	}, {
+		name: "no routable components",
+		app: &godo.App{LiveURL: "https://example.com", Spec: &godo.AppSpec{
+			Workers: []*godo.AppWorkerSpec{{Name: "worker"}},
+		}},
+	}, {
+		name: "ingress rules",
+		app: &godo.App{LiveURL: "https://example.com/", Spec: &godo.AppSpec{
+			Ingress: &godo.AppIngressSpec{Rules: []*godo.AppIngressSpecRule{{
+				Match:     &godo.AppIngressSpecRuleMatch{Path: &godo.AppIngressSpecRuleStringMatch{Prefix: "/"}},
+				Component: &godo.AppIngressSpecRuleRoutingComponent{Name: "web"},
+			}, {
+				Match:     &godo.AppIngressSpecRuleMatch{Path: &godo.AppIngressSpecRuleStringMatch{Prefix: "/api"}},
+				Component: &godo.AppIngressSpecRuleRoutingComponent{Name: "api"},
+			}, {
+				Match:     &godo.AppIngressSpecRuleMatch{Path: &godo.AppIngressSpecRuleStringMatch{Prefix: "/v2"}},
+				Component: &godo.AppIngressSpecRuleRoutingComponent{Name: "api"},
+			}, {
+				Match:    &godo.AppIngressSpecRuleMatch{Path: &godo.AppIngressSpecRuleStringMatch{Prefix: "/old"}},
+				Redirect: &godo.AppIngressSpecRuleRoutingRedirect{Uri: "/new"},
+			}}},
+		}},
+		expected: map[string]string{
+			"web": "https://example.com/",
+			"api": "https://example.com/api",
+		},
+	}, {
+		name: "component routes",
+		app: &godo.App{LiveURL: "https://example.com", Spec: &godo.AppSpec{
+			Services:    []*godo.AppServiceSpec{{Name: "api", Routes: []*godo.AppRouteSpec{{Path: "/api"}}}},
+			StaticSites: []*godo.AppStaticSiteSpec{{Name: "site", Routes: []*godo.AppRouteSpec{{Path: "/"}}}},
+		}},
+		expected: map[string]string{
+			"api":  "https://example.com/api",
+			"site": "https://example.com/",
+		},
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			require.Equal(t, test.expected, componentURLs(test.app))
+		})
+	}
+}
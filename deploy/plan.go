@@ -0,0 +1,372 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/digitalocean/app_action/utils"
+	"github.com/digitalocean/godo"
+)
+
+// changeAction describes what happens to a part of the spec.
+type changeAction string
+
+const (
+	changeAdded   changeAction = "added"
+	changeRemoved changeAction = "removed"
+	changeUpdated changeAction = "updated"
+)
+
+// plan describes the changes a deployment of a spec would apply to an app.
+type plan struct {
+	AppName string `json:"app_name"`
+	Create  bool   `json:"create"`
+	// Fields are the changed top-level fields of the spec apart from its envs
+	// and components, for example domains or ingress.
+	Fields     []string          `json:"fields,omitempty"`
+	Envs       []envChange       `json:"envs,omitempty"`
+	Components []componentChange `json:"components,omitempty"`
+}
+
+// hasChanges returns whether or not the plan would change anything.
+func (p *plan) hasChanges() bool {
+	return p.Create || len(p.Fields) > 0 || len(p.Envs) > 0 || len(p.Components) > 0
+}
+
+// componentChange describes the changes to a single component.
+type componentChange struct {
+	Name          string                `json:"name"`
+	Type          godo.AppComponentType `json:"type"`
+	Action        changeAction          `json:"action"`
+	Image         *valueChange          `json:"image,omitempty"`
+	InstanceSize  *valueChange          `json:"instance_size,omitempty"`
+	InstanceCount *valueChange          `json:"instance_count,omitempty"`
+	Envs          []envChange           `json:"envs,omitempty"`
+}
+
+// valueChange describes a changed value.
+type valueChange struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// envChange describes the change of an environment variable.
+// Values of secrets are never part of the change as they are encrypted in the
+// live spec and can't be compared.
+type envChange struct {
+	Key    string       `json:"key"`
+	Action changeAction `json:"action"`
+	From   string       `json:"from,omitempty"`
+	To     string       `json:"to,omitempty"`
+}
+
+// plan computes the changes deploying the given spec would cause, logs them and
+// surfaces them as the "plan" output. Nothing is changed on the app.
+func (d *deployer) plan(ctx context.Context, spec *godo.AppSpec) (*plan, error) {
+	app, err := utils.FindAppByName(ctx, d.apps, spec.GetName())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get app: %w", err)
+	}
+
+	p, err := diffSpecs(app.GetSpec(), spec)
+	if err != nil {
+		return nil, err
+	}
+	p.Create = app == nil
+
+	d.action.Group("plan")
+	d.action.Infof("%s", p.String())
+	d.action.EndGroup()
+
+	planJSON, err := json.Marshal(p)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal plan: %w", err)
+	}
+	d.action.SetOutput("plan", string(planJSON))
+	return p, nil
+}
+
+// specComponentFields are the top-level fields of the spec holding components.
+var specComponentFields = map[string]bool{
+	"services":     true,
+	"static_sites": true,
+	"workers":      true,
+	"jobs":         true,
+	"functions":    true,
+	"databases":    true,
+}
+
+// diffSpecs computes the changes between the current and the desired spec.
+// current may be nil if the app doesn't exist yet. Like for skip_if_unchanged,
+// values that are unset in the desired spec are assumed to be defaults filled
+// in by App Platform, see specDiff.
+func diffSpecs(current, desired *godo.AppSpec) (*plan, error) {
+	p := &plan{
+		AppName: desired.GetName(),
+		Envs:    diffEnvs(current.GetEnvs(), desired.GetEnvs()),
+	}
+
+	var err error
+	p.Fields, err = diffFields(current, desired)
+	if err != nil {
+		return nil, err
+	}
+
+	currentComponents := componentsByName(current)
+	desiredComponents := componentsByName(desired)
+	for _, name := range sortedKeys(currentComponents, desiredComponents) {
+		cur, des := currentComponents[name], desiredComponents[name]
+		switch {
+		case cur == nil:
+			p.Components = append(p.Components, componentChange{Name: name, Type: des.GetType(), Action: changeAdded})
+		case des == nil:
+			p.Components = append(p.Components, componentChange{Name: name, Type: cur.GetType(), Action: changeRemoved})
+		default:
+			desValue, err := asJSONValue(des)
+			if err != nil {
+				return nil, err
+			}
+			curValue, err := asJSONValue(cur)
+			if err != nil {
+				return nil, err
+			}
+			if specDiff(desValue, curValue, name) != "" {
+				p.Components = append(p.Components, diffComponent(cur, des))
+			}
+		}
+	}
+	return p, nil
+}
+
+// diffFields returns the changed top-level fields of the spec apart from its
+// name, envs and components, which are diffed separately.
+func diffFields(current, desired *godo.AppSpec) ([]string, error) {
+	if current == nil {
+		return nil, nil
+	}
+	cur, err := asJSONValue(current)
+	if err != nil {
+		return nil, err
+	}
+	des, err := asJSONValue(desired)
+	if err != nil {
+		return nil, err
+	}
+	curFields, _ := cur.(map[string]any)
+	desFields, _ := des.(map[string]any)
+
+	var fields []string
+	for _, key := range sortedKeys(curFields, desFields) {
+		if key == "name" || key == "envs" || specComponentFields[key] {
+			continue
+		}
+		// Diff the field on its own to keep specDiff's handling of fields
+		// missing in the desired spec.
+		curField, desField := map[string]any{}, map[string]any{}
+		if v, ok := curFields[key]; ok {
+			curField[key] = v
+		}
+		if v, ok := desFields[key]; ok {
+			desField[key] = v
+		}
+		if specDiff(desField, curField, "spec") != "" {
+			fields = append(fields, key)
+		}
+	}
+	return fields, nil
+}
+
+// diffComponent computes the changes between two versions of the same component.
+// Instance sizes and counts that are unset in the desired component are
+// defaults and not reported.
+func diffComponent(current, desired godo.AppComponentSpec) componentChange {
+	c := componentChange{Name: desired.GetName(), Type: desired.GetType(), Action: changeUpdated}
+
+	cur, curOK := current.(godo.AppContainerComponentSpec)
+	des, desOK := desired.(godo.AppContainerComponentSpec)
+	if curOK && desOK {
+		c.Image = diffValue(imageRef(cur.GetImage()), imageRef(des.GetImage()))
+		if des.GetInstanceSizeSlug() != "" {
+			c.InstanceSize = diffValue(cur.GetInstanceSizeSlug(), des.GetInstanceSizeSlug())
+		}
+		if des.GetInstanceCount() != 0 {
+			c.InstanceCount = diffValue(strconv.FormatInt(cur.GetInstanceCount(), 10), strconv.FormatInt(des.GetInstanceCount(), 10))
+		}
+	}
+
+	type withEnvs interface {
+		GetEnvs() []*godo.AppVariableDefinition
+	}
+	curEnvs, curOK := current.(withEnvs)
+	desEnvs, desOK := desired.(withEnvs)
+	if curOK && desOK {
+		c.Envs = diffEnvs(curEnvs.GetEnvs(), desEnvs.GetEnvs())
+	}
+	return c
+}
+
+// diffEnvs computes the changes between two sets of environment variables.
+func diffEnvs(current, desired []*godo.AppVariableDefinition) []envChange {
+	currentByKey := make(map[string]*godo.AppVariableDefinition, len(current))
+	for _, e := range current {
+		currentByKey[e.Key] = e
+	}
+	desiredByKey := make(map[string]*godo.AppVariableDefinition, len(desired))
+	for _, e := range desired {
+		desiredByKey[e.Key] = e
+	}
+
+	var changes []envChange
+	for _, key := range sortedKeys(currentByKey, desiredByKey) {
+		cur, des := currentByKey[key], desiredByKey[key]
+		switch {
+		case cur == nil:
+			changes = append(changes, envChange{Key: key, Action: changeAdded, To: envValue(des)})
+		case des == nil:
+			changes = append(changes, envChange{Key: key, Action: changeRemoved, From: envValue(cur)})
+		case cur.Type == godo.AppVariableType_Secret && des.Type == godo.AppVariableType_Secret:
+			// Secret values are encrypted in the live spec, so we can't tell if they changed.
+			if !envDefaultEqual(cur.Scope, des.Scope, godo.AppVariableScope_RunAndBuildTime) {
+				changes = append(changes, envChange{Key: key, Action: changeUpdated})
+			}
+		case cur.Value != des.Value ||
+			!envDefaultEqual(cur.Type, des.Type, godo.AppVariableType_General) ||
+			!envDefaultEqual(cur.Scope, des.Scope, godo.AppVariableScope_RunAndBuildTime):
+			changes = append(changes, envChange{Key: key, Action: changeUpdated, From: envValue(cur), To: envValue(des)})
+		}
+	}
+	return changes
+}
+
+// envDefaultEqual returns whether the current and desired values of an env's
+// field are equal, given the default App Platform fills in for unset values.
+func envDefaultEqual[T ~string](current, desired, def T) bool {
+	if current == "" {
+		current = def
+	}
+	if desired == "" {
+		desired = def
+	}
+	return current == desired
+}
+
+// envValue returns the value of the environment variable as it's safe to print.
+func envValue(e *godo.AppVariableDefinition) string {
+	if e.Type == godo.AppVariableType_Secret {
+		return ""
+	}
+	return e.Value
+}
+
+// diffValue returns a change if the values differ and nil otherwise.
+func diffValue(from, to string) *valueChange {
+	if from == to {
+		return nil
+	}
+	return &valueChange{From: from, To: to}
+}
+
+// imageRef returns a human-readable reference to the given image.
+func imageRef(image *godo.ImageSourceSpec) string {
+	if image == nil {
+		return ""
+	}
+	ref := image.Repository
+	if image.Registry != "" {
+		ref = image.Registry + "/" + ref
+	}
+	if image.Digest != "" {
+		return ref + "@" + image.Digest
+	}
+	tag := image.Tag
+	if tag == "" {
+		tag = "latest"
+	}
+	return ref + ":" + tag
+}
+
+// componentsByName returns all components of the spec keyed by their name.
+func componentsByName(spec *godo.AppSpec) map[string]godo.AppComponentSpec {
+	components := make(map[string]godo.AppComponentSpec)
+	if spec == nil {
+		return components
+	}
+	_ = spec.ForEachAppComponentSpec(func(c godo.AppComponentSpec) error {
+		components[c.GetName()] = c
+		return nil
+	})
+	return components
+}
+
+// sortedKeys returns the union of the keys of both maps in sorted order.
+func sortedKeys[V any](a, b map[string]V) []string {
+	keys := make([]string, 0, len(a)+len(b))
+	for k := range a {
+		keys = append(keys, k)
+	}
+	for k := range b {
+		if _, ok := a[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// String renders the plan in a human-readable form.
+func (p *plan) String() string {
+	var b strings.Builder
+	switch {
+	case p.Create:
+		fmt.Fprintf(&b, "app %q will be created", p.AppName)
+	case !p.hasChanges():
+		fmt.Fprintf(&b, "app %q is up to date, no changes", p.AppName)
+	default:
+		fmt.Fprintf(&b, "app %q will be updated", p.AppName)
+	}
+	for _, f := range p.Fields {
+		fmt.Fprintf(&b, "\n%s %s", actionSymbol(changeUpdated), f)
+	}
+	for _, e := range p.Envs {
+		fmt.Fprintf(&b, "\n%s", e.String())
+	}
+	for _, c := range p.Components {
+		fmt.Fprintf(&b, "\n%s %s %q", actionSymbol(c.Action), c.Type, c.Name)
+		if c.Image != nil {
+			fmt.Fprintf(&b, "\n    image: %s -> %s", c.Image.From, c.Image.To)
+		}
+		if c.InstanceSize != nil {
+			fmt.Fprintf(&b, "\n    instance size: %s -> %s", c.InstanceSize.From, c.InstanceSize.To)
+		}
+		if c.InstanceCount != nil {
+			fmt.Fprintf(&b, "\n    instance count: %s -> %s", c.InstanceCount.From, c.InstanceCount.To)
+		}
+		for _, e := range c.Envs {
+			fmt.Fprintf(&b, "\n    %s", e.String())
+		}
+	}
+	return b.String()
+}
+
+// String renders the env change in a human-readable form.
+func (e envChange) String() string {
+	if e.Action == changeUpdated && (e.From != "" || e.To != "") {
+		return fmt.Sprintf("%s env %s: %q -> %q", actionSymbol(e.Action), e.Key, e.From, e.To)
+	}
+	return fmt.Sprintf("%s env %s", actionSymbol(e.Action), e.Key)
+}
+
+// actionSymbol returns a diff-like symbol for the given action.
+func actionSymbol(a changeAction) string {
+	switch a {
+	case changeAdded:
+		return "+"
+	case changeRemoved:
+		return "-"
+	}
+	return "~"
+}
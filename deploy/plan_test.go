@@ -0,0 +1,263 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"testing"
+
+	"github.com/digitalocean/godo"
+	gha "github.com/sethvargo/go-githubactions"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiffSpecs(t *testing.T) {
+	current := &godo.AppSpec{
+		Name: "foo",
+		Envs: []*godo.AppVariableDefinition{{
+			Key:   "GLOBAL",
+			Value: "1",
+		}, {
+			Key:   "REMOVED",
+			Value: "gone",
+		}},
+		Services: []*godo.AppServiceSpec{{
+			Name:             "web",
+			InstanceSizeSlug: "apps-s-1vcpu-1gb",
+			InstanceCount:    1,
+			Image: &godo.ImageSourceSpec{
+				Registry:   "foo",
+				Repository: "bar",
+				Tag:        "v1",
+			},
+			Envs: []*godo.AppVariableDefinition{{
+				Key:   "SECRET",
+				Value: "EV[1:encrypted]",
+				Type:  godo.AppVariableType_Secret,
+			}, {
+				Key:   "PLAIN",
+				Value: "a",
+			}},
+		}, {
+			Name: "unchanged",
+		}},
+		Jobs: []*godo.AppJobSpec{{
+			Name: "migrate",
+		}},
+	}
+	desired := &godo.AppSpec{
+		Name: "foo",
+		Envs: []*godo.AppVariableDefinition{{
+			Key:   "GLOBAL",
+			Value: "2",
+		}, {
+			Key:   "ADDED",
+			Value: "new",
+		}},
+		Services: []*godo.AppServiceSpec{{
+			Name:             "web",
+			InstanceSizeSlug: "apps-s-1vcpu-2gb",
+			InstanceCount:    1,
+			Image: &godo.ImageSourceSpec{
+				Registry:   "foo",
+				Repository: "bar",
+				Digest:     "sha256:123",
+			},
+			Envs: []*godo.AppVariableDefinition{{
+				Key:   "SECRET",
+				Value: "plaintext",
+				Type:  godo.AppVariableType_Secret,
+			}, {
+				Key:   "PLAIN",
+				Value: "b",
+			}, {
+				Key:   "NEW_SECRET",
+				Value: "plaintext",
+				Type:  godo.AppVariableType_Secret,
+			}},
+		}, {
+			Name: "unchanged",
+		}},
+		Workers: []*godo.AppWorkerSpec{{
+			Name: "worker",
+		}},
+	}
+
+	got, err := diffSpecs(current, desired)
+	require.NoError(t, err)
+
+	expected := &plan{
+		AppName: "foo",
+		Envs: []envChange{{
+			Key:    "ADDED",
+			Action: changeAdded,
+			To:     "new",
+		}, {
+			Key:    "GLOBAL",
+			Action: changeUpdated,
+			From:   "1",
+			To:     "2",
+		}, {
+			Key:    "REMOVED",
+			Action: changeRemoved,
+			From:   "gone",
+		}},
+		Components: []componentChange{{
+			Name:   "migrate",
+			Type:   godo.AppComponentTypeJob,
+			Action: changeRemoved,
+		}, {
+			Name:   "web",
+			Type:   godo.AppComponentTypeService,
+			Action: changeUpdated,
+			Image: &valueChange{
+				From: "foo/bar:v1",
+				To:   "foo/bar@sha256:123",
+			},
+			InstanceSize: &valueChange{
+				From: "apps-s-1vcpu-1gb",
+				To:   "apps-s-1vcpu-2gb",
+			},
+			Envs: []envChange{{
+				Key:    "NEW_SECRET",
+				Action: changeAdded, // Secret value is not exposed.
+			}, {
+				Key:    "PLAIN",
+				Action: changeUpdated,
+				From:   "a",
+				To:     "b",
+			}}, // Unchanged secret is not reported.
+		}, {
+			Name:   "worker",
+			Type:   godo.AppComponentTypeWorker,
+			Action: changeAdded,
+		}},
+	}
+	require.Equal(t, expected, got)
+
+	require.Equal(t, `app "foo" will be updated
++ env ADDED
+~ env GLOBAL: "1" -> "2"
+- env REMOVED
+- job "migrate"
+~ service "web"
+    image: foo/bar:v1 -> foo/bar@sha256:123
+    instance size: apps-s-1vcpu-1gb -> apps-s-1vcpu-2gb
+    + env NEW_SECRET
+    ~ env PLAIN: "a" -> "b"
++ worker "worker"`, got.String())
+}
+
+func TestDiffSpecsDefaultsAndFields(t *testing.T) {
+	desired := func() *godo.AppSpec {
+		return &godo.AppSpec{
+			Name: "foo",
+			Services: []*godo.AppServiceSpec{{
+				Name:  "web",
+				Image: &godo.ImageSourceSpec{Registry: "foo", Repository: "bar", Tag: "v1"},
+				Envs:  []*godo.AppVariableDefinition{{Key: "PLAIN", Value: "a"}},
+			}},
+			Domains: []*godo.AppDomainSpec{{Domain: "foo.com"}},
+		}
+	}
+	// current mimics App Platform filling in defaults.
+	current := func() *godo.AppSpec {
+		spec := desired()
+		spec.Region = "ams"
+		spec.Alerts = []*godo.AppAlertSpec{{Rule: godo.AppAlertSpecRule_DeploymentFailed}}
+		spec.Services[0].InstanceSizeSlug = "apps-s-1vcpu-0.5gb"
+		spec.Services[0].InstanceCount = 1
+		spec.Services[0].Envs[0].Type = godo.AppVariableType_General
+		spec.Services[0].Envs[0].Scope = godo.AppVariableScope_RunAndBuildTime
+		return spec
+	}
+
+	got, err := diffSpecs(current(), desired())
+	require.NoError(t, err)
+	require.False(t, got.hasChanges(), got.String())
+
+	changed := desired()
+	changed.Domains[0].Domain = "bar.com"
+	changed.Region = "nyc"
+	changed.Ingress = &godo.AppIngressSpec{Rules: []*godo.AppIngressSpecRule{{
+		Component: &godo.AppIngressSpecRuleRoutingComponent{Name: "web"},
+	}}}
+	changed.Services[0].HTTPPort = 8080
+	got, err = diffSpecs(current(), changed)
+	require.NoError(t, err)
+	require.Equal(t, []string{"domains", "ingress", "region"}, got.Fields)
+	require.Equal(t, `app "foo" will be updated
+~ domains
+~ ingress
+~ region
+~ service "web"`, got.String())
+}
+
+func TestPlan(t *testing.T) {
+	ctx := context.Background()
+	spec := &godo.AppSpec{
+		Name:     "foo",
+		Services: []*godo.AppServiceSpec{{Name: "web"}},
+	}
+
+	tests := []struct {
+		name           string
+		apps           []*godo.App
+		expectedLogs   []byte
+		expectedOutput []byte
+	}{{
+		name: "new app",
+		expectedLogs: []byte(`::group::plan
+app "foo" will be created
++ service "web"
+::endgroup::
+`),
+		expectedOutput: []byte(`plan<<_GitHubActionsFileCommandDelimeter_
+{"app_name":"foo","create":true,"components":[{"name":"web","type":"service","action":"added"}]}
+_GitHubActionsFileCommandDelimeter_
+`),
+	}, {
+		name: "up to date app",
+		apps: []*godo.App{{ID: "app-id", Spec: spec}},
+		expectedLogs: []byte(`::group::plan
+app "foo" is up to date, no changes
+::endgroup::
+`),
+		expectedOutput: []byte(`plan<<_GitHubActionsFileCommandDelimeter_
+{"app_name":"foo","create":false}
+_GitHubActionsFileCommandDelimeter_
+`),
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			as := &mockedAppsService{}
+			as.On("List", ctx, mock.Anything).Return(test.apps, &godo.Response{}, nil)
+
+			var actionLogs bytes.Buffer
+			outputFilePath := t.TempDir() + "/output"
+			d := &deployer{
+				action: gha.New(gha.WithWriter(&actionLogs), gha.WithGetenv(func(k string) string {
+					switch k {
+					case "GITHUB_OUTPUT":
+						return outputFilePath
+					default:
+						return ""
+					}
+				})),
+				apps: as,
+			}
+			_, err := d.plan(ctx, spec)
+			require.NoError(t, err)
+			require.Equal(t, test.expectedLogs, actionLogs.Bytes())
+
+			output, err := os.ReadFile(outputFilePath)
+			require.NoError(t, err)
+			require.Equal(t, test.expectedOutput, output)
+
+			// Nothing else must've been called.
+			as.AssertExpectations(t)
+		})
+	}
+}
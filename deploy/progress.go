@@ -0,0 +1,94 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/digitalocean/godo"
+)
+
+// progressStep is a single step of a deployment's progress. Nested steps are
+// flattened, with their name being the path of step names leading to them.
+type progressStep struct {
+	Name      string                            `json:"name"`
+	Component string                            `json:"component,omitempty"`
+	Status    godo.DeploymentProgressStepStatus `json:"status"`
+	StartedAt *time.Time                        `json:"started_at,omitempty"`
+	EndedAt   *time.Time                        `json:"ended_at,omitempty"`
+	Reason    string                            `json:"reason,omitempty"`
+}
+
+// flattenSteps flattens the given progress steps into a list of steps,
+// parents coming before their children.
+func flattenSteps(steps []*godo.DeploymentProgressStep) []progressStep {
+	return appendSteps(nil, "", steps)
+}
+
+// appendSteps appends the given steps and their children to flattened.
+func appendSteps(flattened []progressStep, prefix string, steps []*godo.DeploymentProgressStep) []progressStep {
+	for _, step := range steps {
+		s := progressStep{
+			Name:      prefix + step.GetName(),
+			Component: step.GetComponentName(),
+			Status:    step.GetStatus(),
+			Reason:    step.GetReason().GetMessage(),
+		}
+		if t := step.GetStartedAt(); !t.IsZero() {
+			s.StartedAt = &t
+		}
+		if t := step.GetEndedAt(); !t.IsZero() {
+			s.EndedAt = &t
+		}
+		flattened = append(flattened, s)
+		flattened = appendSteps(flattened, s.Name+"/", step.GetSteps())
+	}
+	return flattened
+}
+
+// logStepTransitions logs all steps of the deployment whose status changed
+// compared to the given statuses and updates them accordingly.
+func (d *deployer) logStepTransitions(statuses map[string]godo.DeploymentProgressStepStatus, dep *godo.Deployment) {
+	for _, step := range flattenSteps(dep.GetProgress().GetSteps()) {
+		if statuses[step.Name] == step.Status {
+			continue
+		}
+		statuses[step.Name] = step.Status
+
+		if step.Reason != "" {
+			d.action.Infof("step %s is %s: %s", step.Name, step.Status, step.Reason)
+		} else {
+			d.action.Infof("step %s is %s", step.Name, step.Status)
+		}
+	}
+}
+
+// failedStepReason returns a description of the most specific failed step
+// of the deployment or an empty string if there is none.
+func failedStepReason(dep *godo.Deployment) string {
+	var failed *progressStep
+	for _, step := range flattenSteps(dep.GetProgress().GetSteps()) {
+		if step.Status == godo.DeploymentProgressStepStatus_Error && step.Reason != "" {
+			failed = &step
+		}
+	}
+	if failed == nil {
+		return ""
+	}
+	return fmt.Sprintf("step %s failed: %s", failed.Name, failed.Reason)
+}
+
+// setStepsOutput surfaces the full step timeline of the deployment as the
+// "deployment_steps" output.
+func (d *deployer) setStepsOutput(dep *godo.Deployment) error {
+	steps := flattenSteps(dep.GetProgress().GetSteps())
+	if len(steps) == 0 {
+		return nil
+	}
+	stepsJSON, err := json.Marshal(steps)
+	if err != nil {
+		return fmt.Errorf("failed to marshal deployment steps: %w", err)
+	}
+	d.action.SetOutput("deployment_steps", string(stepsJSON))
+	return nil
+}
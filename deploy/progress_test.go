@@ -0,0 +1,104 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/digitalocean/godo"
+	gha "github.com/sethvargo/go-githubactions"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFlattenSteps(t *testing.T) {
+	started := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	ended := started.Add(time.Minute)
+
+	got := flattenSteps([]*godo.DeploymentProgressStep{{
+		Name:      "build",
+		Status:    godo.DeploymentProgressStepStatus_Success,
+		StartedAt: started,
+		EndedAt:   ended,
+		Steps: []*godo.DeploymentProgressStep{{
+			Name:          "web",
+			ComponentName: "web",
+			Status:        godo.DeploymentProgressStepStatus_Success,
+		}},
+	}, {
+		Name:   "deploy",
+		Status: godo.DeploymentProgressStepStatus_Error,
+		Reason: &godo.DeploymentProgressStepReason{Message: "health checks failed"},
+	}})
+
+	require.Equal(t, []progressStep{{
+		Name:      "build",
+		Status:    godo.DeploymentProgressStepStatus_Success,
+		StartedAt: &started,
+		EndedAt:   &ended,
+	}, {
+		Name:      "build/web",
+		Component: "web",
+		Status:    godo.DeploymentProgressStepStatus_Success,
+	}, {
+		Name:   "deploy",
+		Status: godo.DeploymentProgressStepStatus_Error,
+		Reason: "health checks failed",
+	}}, got)
+}
+
+func TestLogStepTransitions(t *testing.T) {
+	var actionLogs bytes.Buffer
+	d := &deployer{action: gha.New(gha.WithWriter(&actionLogs))}
+	statuses := make(map[string]godo.DeploymentProgressStepStatus)
+
+	d.logStepTransitions(statuses, &godo.Deployment{Progress: &godo.DeploymentProgress{
+		Steps: []*godo.DeploymentProgressStep{{
+			Name:   "build",
+			Status: godo.DeploymentProgressStepStatus_Running,
+			Steps: []*godo.DeploymentProgressStep{{
+				Name:   "web",
+				Status: godo.DeploymentProgressStepStatus_Running,
+			}},
+		}, {
+			Name:   "deploy",
+			Status: godo.DeploymentProgressStepStatus_Pending,
+		}},
+	}})
+	d.logStepTransitions(statuses, &godo.Deployment{Progress: &godo.DeploymentProgress{
+		Steps: []*godo.DeploymentProgressStep{{
+			Name:   "build",
+			Status: godo.DeploymentProgressStepStatus_Error,
+			Steps: []*godo.DeploymentProgressStep{{
+				Name:   "web",
+				Status: godo.DeploymentProgressStepStatus_Error,
+				Reason: &godo.DeploymentProgressStepReason{Message: "build command failed"},
+			}},
+		}, {
+			Name:   "deploy",
+			Status: godo.DeploymentProgressStepStatus_Pending,
+		}},
+	}})
+
+	require.Equal(t, []byte(`step build is RUNNING
+step build/web is RUNNING
+step deploy is PENDING
+step build is ERROR
+step build/web is ERROR: build command failed
+`), actionLogs.Bytes())
+}
+
+func TestFailedStepReason(t *testing.T) {
+	require.Equal(t, "", failedStepReason(&godo.Deployment{}))
+	require.Equal(t, "step build/web failed: build command failed", failedStepReason(&godo.Deployment{Progress: &godo.DeploymentProgress{
+		Steps: []*godo.DeploymentProgressStep{{
+			Name:   "build",
+			Status: godo.DeploymentProgressStepStatus_Error,
+			Reason: &godo.DeploymentProgressStepReason{Message: "component failed"},
+			Steps: []*godo.DeploymentProgressStep{{
+				Name:   "web",
+				Status: godo.DeploymentProgressStepStatus_Error,
+				Reason: &godo.DeploymentProgressStepReason{Message: "build command failed"},
+			}},
+		}},
+	}}))
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/digitalocean/app_action/utils"
+	"github.com/digitalocean/godo"
+)
+
+// redactedValue replaces redacted values in the app output.
+const redactedValue = "[REDACTED]"
+
+// maskSecrets registers the values of all secret environment variables and
+// all registry credentials of the spec with the action's log masking.
+func maskSecrets(a utils.Runtime, spec *godo.AppSpec) {
+	forEachSensitiveValue(spec, false, func(value *string) {
+		// Masks only apply to single lines, so multi-line secrets are masked line by line.
+		for _, line := range strings.Split(*value, "\n") {
+			if strings.TrimSpace(line) != "" {
+				a.AddMask(line)
+			}
+		}
+	})
+}
+
+// setAppOutput surfaces a redacted JSON representation of the app as the
+// "app" output.
+func setAppOutput(a utils.Runtime, app *godo.App, redactAllEnvs bool) error {
+	redacted, err := redactApp(app, redactAllEnvs)
+	if err != nil {
+		return err
+	}
+	appJSON, err := json.Marshal(redacted)
+	if err != nil {
+		return fmt.Errorf("failed to marshal app: %w", err)
+	}
+	a.SetOutput("app", string(appJSON))
+	return nil
+}
+
+// redactApp returns a copy of the app with the values of all secret
+// environment variables and all registry credentials replaced by a
+// placeholder. If all is true, the values of all environment variables are
+// replaced.
+func redactApp(app *godo.App, all bool) (*godo.App, error) {
+	appJSON, err := json.Marshal(app)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal app: %w", err)
+	}
+	var redacted *godo.App
+	if err := json.Unmarshal(appJSON, &redacted); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal app: %w", err)
+	}
+
+	specs := []*godo.AppSpec{redacted.GetSpec()}
+	for _, dep := range []*godo.Deployment{redacted.ActiveDeployment, redacted.InProgressDeployment, redacted.PendingDeployment, redacted.PinnedDeployment} {
+		specs = append(specs, dep.GetSpec())
+	}
+	for _, spec := range specs {
+		forEachSensitiveValue(spec, all, func(value *string) {
+			*value = redactedValue
+		})
+	}
+	return redacted, nil
+}
+
+// forEachSensitiveValue calls fn with a pointer to every non-empty secret
+// environment variable value and registry credential of the spec. If allEnvs
+// is true, fn is called for the values of all environment variables.
+func forEachSensitiveValue(spec *godo.AppSpec, allEnvs bool, fn func(value *string)) {
+	visitEnvs := func(envs []*godo.AppVariableDefinition) {
+		for _, env := range envs {
+			if env.Value != "" && (allEnvs || env.Type == godo.AppVariableType_Secret) {
+				fn(&env.Value)
+			}
+		}
+	}
+
+	visitEnvs(spec.GetEnvs())
+	_ = spec.ForEachAppComponentSpec(func(c godo.AppComponentSpec) error {
+		if bc, ok := c.(godo.AppBuildableComponentSpec); ok {
+			visitEnvs(bc.GetEnvs())
+		}
+		if cc, ok := c.(godo.AppContainerComponentSpec); ok {
+			if image := cc.GetImage(); image.GetRegistryCredentials() != "" {
+				fn(&image.RegistryCredentials)
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/digitalocean/godo"
+	gha "github.com/sethvargo/go-githubactions"
+	"github.com/stretchr/testify/require"
+)
+
+func redactTestSpec() *godo.AppSpec {
+	return &godo.AppSpec{
+		Name: "foo",
+		Envs: []*godo.AppVariableDefinition{{
+			Key:   "GLOBAL",
+			Value: "global",
+		}, {
+			Key:   "GLOBAL_SECRET",
+			Value: "global-secret",
+			Type:  godo.AppVariableType_Secret,
+		}},
+		Services: []*godo.AppServiceSpec{{
+			Name: "web",
+			Image: &godo.ImageSourceSpec{
+				RegistryType:        godo.ImageSourceSpecRegistryType_DockerHub,
+				Repository:          "foo/bar",
+				RegistryCredentials: "user:token",
+			},
+			Envs: []*godo.AppVariableDefinition{{
+				Key:   "SECRET",
+				Value: "line1\nline2",
+				Type:  godo.AppVariableType_Secret,
+			}, {
+				Key:  "EMPTY_SECRET",
+				Type: godo.AppVariableType_Secret,
+			}},
+		}},
+	}
+}
+
+func TestMaskSecrets(t *testing.T) {
+	var actionLogs bytes.Buffer
+	a := gha.New(gha.WithWriter(&actionLogs))
+
+	maskSecrets(a, redactTestSpec())
+
+	require.Equal(t, `::add-mask::global-secret
+::add-mask::line1
+::add-mask::line2
+::add-mask::user:token
+`, actionLogs.String())
+}
+
+func TestRedactApp(t *testing.T) {
+	tests := []struct {
+		name     string
+		all      bool
+		expected func(spec *godo.AppSpec)
+	}{{
+		name: "secrets",
+		expected: func(spec *godo.AppSpec) {
+			spec.Envs[1].Value = redactedValue
+			spec.Services[0].Image.RegistryCredentials = redactedValue
+			spec.Services[0].Envs[0].Value = redactedValue
+		},
+	}, {
+		name: "all",
+		all:  true,
+		expected: func(spec *godo.AppSpec) {
+			spec.Envs[0].Value = redactedValue
+			spec.Envs[1].Value = redactedValue
+			spec.Services[0].Image.RegistryCredentials = redactedValue
+			spec.Services[0].Envs[0].Value = redactedValue
+		},
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			app := &godo.App{
+				ID:               "app-id",
+				Spec:             redactTestSpec(),
+				ActiveDeployment: &godo.Deployment{ID: "deployment-id", Spec: redactTestSpec()},
+			}
+
+			redacted, err := redactApp(app, test.all)
+			require.NoError(t, err)
+
+			expectedSpec := redactTestSpec()
+			test.expected(expectedSpec)
+			expectedDeploymentSpec := redactTestSpec()
+			test.expected(expectedDeploymentSpec)
+			require.Equal(t, &godo.App{
+				ID:               "app-id",
+				Spec:             expectedSpec,
+				ActiveDeployment: &godo.Deployment{ID: "deployment-id", Spec: expectedDeploymentSpec},
+			}, redacted)
+
+			// The original app must not be modified.
+			require.Equal(t, redactTestSpec(), app.Spec)
+		})
+	}
+}
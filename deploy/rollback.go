@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/digitalocean/app_action/utils"
+	"github.com/digitalocean/godo"
+)
+
+// rollbackSearchDepth is the amount of recent deployments searched for a
+// deployment to roll back to.
+const rollbackSearchDepth = 20
+
+// rollback rolls the app back to the last active deployment before the failed
+// deployment and waits for the rollback to finish.
+// The passed context is likely done or close to its deadline already, so the
+// rollback uses a detached context bounded by rollbackTimeout.
+func (d *deployer) rollback(ctx context.Context, appID, failedDeploymentID string) error {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), rollbackTimeout)
+	defer cancel()
+
+	d.action.SetOutput("failed_deployment_id", failedDeploymentID)
+
+	target, err := d.findRollbackTarget(ctx, appID, failedDeploymentID)
+	if err != nil {
+		return fmt.Errorf("failed to find deployment to roll back to: %w", err)
+	}
+	if target == nil {
+		return errors.New("no previously active deployment to roll back to")
+	}
+
+	d.action.Infof("rolling back to deployment %s", target.GetID())
+	// Skip pinning the app to the rollback to keep future deployments working.
+	dep, _, err := d.deployments.Rollback(ctx, appID, &utils.AppRollbackRequest{DeploymentID: target.GetID(), SkipPin: true})
+	if err != nil {
+		return fmt.Errorf("failed to roll back: %w", err)
+	}
+
+	d.action.Infof("wait for rollback deployment %s to finish", dep.GetID())
+	dep, err = d.waitForRollback(ctx, appID, dep.GetID())
+	if err != nil {
+		return fmt.Errorf("failed to wait for rollback to finish: %w", err)
+	}
+	if dep.GetPhase() != godo.DeploymentPhase_Active {
+		return fmt.Errorf("rollback failed in phase %q", dep.GetPhase())
+	}
+
+	d.action.SetOutput("restored_deployment_id", target.GetID())
+	d.action.Infof("rolled back to deployment %s", target.GetID())
+	return nil
+}
+
+// waitForRollback waits for the given rollback deployment to be in a terminal
+// state. Unlike waitForDeploymentTerminal, it never cancels the deployment, as
+// that would leave the app without a working deployment.
+func (d *deployer) waitForRollback(ctx context.Context, appID, deploymentID string) (*godo.Deployment, error) {
+	t := time.NewTicker(pollInterval)
+	defer t.Stop()
+
+	var currentPhase godo.DeploymentPhase
+	for {
+		dep, _, err := d.apps.GetDeployment(ctx, appID, deploymentID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get deployment: %w", err)
+		}
+		if currentPhase != dep.GetPhase() {
+			d.action.Infof("deployment is in phase: %s", dep.GetPhase())
+			currentPhase = dep.GetPhase()
+		}
+		if isInTerminalPhase(dep) {
+			return dep, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("rollback deployment %s didn't finish within %s, it keeps running in the background", deploymentID, rollbackTimeout)
+		case <-t.C:
+		}
+	}
+}
+
+// findRollbackTarget returns the deployment to roll back to if the given
+// deployment failed, or nil if there is none.
+// That is the currently active deployment, or if the failed deployment itself
+// went active, the deployment that was active before it.
+func (d *deployer) findRollbackTarget(ctx context.Context, appID, failedDeploymentID string) (*godo.Deployment, error) {
+	ds, _, err := d.apps.ListDeployments(ctx, appID, &godo.ListOptions{PerPage: rollbackSearchDepth})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list deployments: %w", err)
+	}
+
+	var previousDeploymentID string
+	for _, dep := range ds {
+		if dep.GetID() == failedDeploymentID {
+			previousDeploymentID = dep.PreviousDeploymentID
+			continue
+		}
+		if dep.GetPhase() == godo.DeploymentPhase_Active {
+			return dep, nil
+		}
+	}
+
+	if previousDeploymentID == "" {
+		return nil, nil
+	}
+	for _, dep := range ds {
+		if dep.GetID() == previousDeploymentID {
+			return dep, nil
+		}
+	}
+	return nil, nil
+}
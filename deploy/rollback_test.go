@@ -0,0 +1,136 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"testing"
+
+	"github.com/digitalocean/app_action/utils"
+	"github.com/digitalocean/godo"
+	gha "github.com/sethvargo/go-githubactions"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRollback(t *testing.T) {
+	// The deployment's context is done, which mustn't affect the rollback.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	appID := "app-id"
+	failedID := "failed-id"
+	previousID := "previous-id"
+	rollbackID := "rollback-id"
+
+	tests := []struct {
+		name           string
+		deployments    []*godo.Deployment
+		rollbackPhase  godo.DeploymentPhase
+		expectedErr    string
+		expectedLogs   []byte
+		expectedOutput []byte
+	}{{
+		name: "rolls back to active deployment",
+		deployments: []*godo.Deployment{
+			{ID: failedID, Phase: godo.DeploymentPhase_Error},
+			{ID: previousID, Phase: godo.DeploymentPhase_Active},
+		},
+		rollbackPhase: godo.DeploymentPhase_Active,
+		expectedLogs: []byte(`rolling back to deployment previous-id
+wait for rollback deployment rollback-id to finish
+deployment is in phase: ACTIVE
+rolled back to deployment previous-id
+`),
+		expectedOutput: []byte(`failed_deployment_id<<_GitHubActionsFileCommandDelimeter_
+failed-id
+_GitHubActionsFileCommandDelimeter_
+restored_deployment_id<<_GitHubActionsFileCommandDelimeter_
+previous-id
+_GitHubActionsFileCommandDelimeter_
+`),
+	}, {
+		name: "rolls back to previous deployment of active deployment",
+		deployments: []*godo.Deployment{
+			{ID: failedID, Phase: godo.DeploymentPhase_Active, PreviousDeploymentID: previousID},
+			{ID: previousID, Phase: godo.DeploymentPhase_Superseded},
+		},
+		rollbackPhase: godo.DeploymentPhase_Active,
+		expectedLogs: []byte(`rolling back to deployment previous-id
+wait for rollback deployment rollback-id to finish
+deployment is in phase: ACTIVE
+rolled back to deployment previous-id
+`),
+		expectedOutput: []byte(`failed_deployment_id<<_GitHubActionsFileCommandDelimeter_
+failed-id
+_GitHubActionsFileCommandDelimeter_
+restored_deployment_id<<_GitHubActionsFileCommandDelimeter_
+previous-id
+_GitHubActionsFileCommandDelimeter_
+`),
+	}, {
+		name: "no deployment to roll back to",
+		deployments: []*godo.Deployment{
+			{ID: failedID, Phase: godo.DeploymentPhase_Error},
+		},
+		expectedErr: "no previously active deployment to roll back to",
+		expectedOutput: []byte(`failed_deployment_id<<_GitHubActionsFileCommandDelimeter_
+failed-id
+_GitHubActionsFileCommandDelimeter_
+`),
+	}, {
+		name: "rollback fails",
+		deployments: []*godo.Deployment{
+			{ID: failedID, Phase: godo.DeploymentPhase_Error},
+			{ID: previousID, Phase: godo.DeploymentPhase_Active},
+		},
+		rollbackPhase: godo.DeploymentPhase_Error,
+		expectedErr:   `rollback failed in phase "ERROR"`,
+		expectedLogs: []byte(`rolling back to deployment previous-id
+wait for rollback deployment rollback-id to finish
+deployment is in phase: ERROR
+`),
+		expectedOutput: []byte(`failed_deployment_id<<_GitHubActionsFileCommandDelimeter_
+failed-id
+_GitHubActionsFileCommandDelimeter_
+`),
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			as := &mockedAppsService{}
+			as.On("ListDeployments", mock.Anything, appID, &godo.ListOptions{PerPage: rollbackSearchDepth}).Return(test.deployments, &godo.Response{}, nil)
+			as.On("GetDeployment", mock.Anything, appID, rollbackID).Return(&godo.Deployment{ID: rollbackID, Phase: test.rollbackPhase}, &godo.Response{}, nil).Maybe()
+			ds := &mockedDeploymentsService{}
+			ds.On("Rollback", mock.Anything, appID, &utils.AppRollbackRequest{DeploymentID: previousID, SkipPin: true}).Return(&godo.Deployment{ID: rollbackID}, &godo.Response{}, nil).Maybe()
+
+			var actionLogs bytes.Buffer
+			outputFilePath := t.TempDir() + "/output"
+			d := &deployer{
+				action: gha.New(gha.WithWriter(&actionLogs), gha.WithGetenv(func(k string) string {
+					switch k {
+					case "GITHUB_OUTPUT":
+						return outputFilePath
+					default:
+						return ""
+					}
+				})),
+				apps:        as,
+				deployments: ds,
+			}
+			err := d.rollback(ctx, appID, failedID)
+			if test.expectedErr != "" {
+				require.EqualError(t, err, test.expectedErr)
+			} else {
+				require.NoError(t, err)
+			}
+			require.Equal(t, test.expectedLogs, actionLogs.Bytes())
+
+			output, err := os.ReadFile(outputFilePath)
+			require.NoError(t, err)
+			require.Equal(t, test.expectedOutput, output)
+
+			as.AssertExpectations(t)
+			ds.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/digitalocean/app_action/utils"
+	"github.com/digitalocean/godo"
+)
+
+// summaryLogTailLines is the amount of log lines of a failed deployment shown in the summary.
+const summaryLogTailLines = 50
+
+// writeSummary writes a Markdown report of the deployment to the job summary.
+func (d *deployer) writeSummary(spec *godo.AppSpec, res *result, deployErr error) {
+	d.action.AddStepSummary(renderSummary(spec, res, deployErr))
+}
+
+// renderSummary renders a Markdown report of the deployment.
+func renderSummary(spec *godo.AppSpec, res *result, deployErr error) string {
+	var b strings.Builder
+	switch {
+	case res.skipped:
+		fmt.Fprintf(&b, "## :white_check_mark: Deployment of `%s` skipped as nothing changed\n\n", spec.GetName())
+	case deployErr == nil:
+		fmt.Fprintf(&b, "## :white_check_mark: Deployment of `%s` succeeded\n\n", spec.GetName())
+	case errors.Is(deployErr, errSuperseded):
+		fmt.Fprintf(&b, "## :fast_forward: Deployment of `%s` was superseded\n\n", spec.GetName())
+	default:
+		fmt.Fprintf(&b, "## :x: Deployment of `%s` failed\n\n", spec.GetName())
+	}
+
+	b.WriteString("| | |\n|---|---|\n")
+	if res.app != nil {
+		fmt.Fprintf(&b, "| App | `%s` (`%s`) |\n", spec.GetName(), res.app.GetID())
+	} else {
+		fmt.Fprintf(&b, "| App | `%s` |\n", spec.GetName())
+	}
+	if res.deployment != nil {
+		fmt.Fprintf(&b, "| Deployment | `%s` |\n", res.deployment.GetID())
+		fmt.Fprintf(&b, "| Phase | `%s` |\n", res.deployment.GetPhase())
+	}
+	if liveURL := res.app.GetLiveURL(); liveURL != "" {
+		fmt.Fprintf(&b, "| Live URL | %s |\n", liveURL)
+	}
+
+	if steps := res.deployment.GetProgress().GetSteps(); len(steps) > 0 {
+		b.WriteString("\n### Steps\n\n| Step | Status | Duration |\n|---|---|---|\n")
+		for _, step := range steps {
+			fmt.Fprintf(&b, "| %s | `%s` | %s |\n", step.GetName(), step.GetStatus(), stepDuration(step))
+		}
+	}
+
+	componentSpec := spec
+	if res.app.GetSpec() != nil {
+		componentSpec = res.app.GetSpec()
+	}
+	b.WriteString("\n### Components\n\n| Component | Type | Source |\n|---|---|---|\n")
+	_ = componentSpec.ForEachAppComponentSpec(func(c godo.AppComponentSpec) error {
+		fmt.Fprintf(&b, "| %s | %s | %s |\n", c.GetName(), c.GetType(), componentSource(c))
+		return nil
+	})
+
+	if deployErr != nil && !errors.Is(deployErr, errSuperseded) {
+		fmt.Fprintf(&b, "\n### Error\n\n```\n%v\n```\n", deployErr)
+
+		logType, logs := "deploy", res.deployLogs
+		if len(logs) == 0 {
+			logType, logs = "build", res.buildLogs
+		}
+		if len(logs) > 0 {
+			fmt.Fprintf(&b, "\n<details>\n<summary>Last %d lines of the %s logs</summary>\n\n```\n%s\n```\n</details>\n", summaryLogTailLines, logType, tailLines(logs, summaryLogTailLines))
+		}
+	}
+	return b.String()
+}
+
+// stepDuration returns the human-readable duration of the given step.
+func stepDuration(step *godo.DeploymentProgressStep) string {
+	if step.GetStartedAt().IsZero() {
+		return "-"
+	}
+	if step.GetEndedAt().IsZero() {
+		return "running"
+	}
+	return step.GetEndedAt().Sub(step.GetStartedAt()).Round(time.Second).String()
+}
+
+// componentSource returns a human-readable description of the component's source.
+func componentSource(c godo.AppComponentSpec) string {
+	if cc, ok := c.(godo.AppContainerComponentSpec); ok && cc.GetImage() != nil {
+		return fmt.Sprintf("image `%s`", imageRef(cc.GetImage()))
+	}
+	if bc, ok := c.(godo.AppBuildableComponentSpec); ok {
+		if src := utils.VCSSourceOf(bc); src != nil {
+			return fmt.Sprintf("%s `%s` branch `%s`", src.Type, src.Repo, src.Branch)
+		}
+	}
+	return "-"
+}
+
+// tailLines returns the last n lines of the given logs.
+func tailLines(logs []byte, n int) []byte {
+	lines := bytes.Split(bytes.TrimRight(logs, "\n"), []byte("\n"))
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return bytes.Join(lines, []byte("\n"))
+}
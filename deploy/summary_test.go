@@ -0,0 +1,157 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/digitalocean/godo"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenderSummary(t *testing.T) {
+	started := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	spec := &godo.AppSpec{
+		Name: "foo",
+		Services: []*godo.AppServiceSpec{{
+			Name: "web",
+			Image: &godo.ImageSourceSpec{
+				Registry:   "foo",
+				Repository: "bar",
+				Digest:     "sha256:123",
+			},
+		}},
+		Workers: []*godo.AppWorkerSpec{{
+			Name: "worker",
+			GitHub: &godo.GitHubSourceSpec{
+				Repo:   "foo/bar",
+				Branch: "main",
+			},
+		}},
+		Databases: []*godo.AppDatabaseSpec{{
+			Name: "db",
+		}},
+	}
+
+	tests := []struct {
+		name     string
+		res      *result
+		err      error
+		expected string
+	}{{
+		name: "success",
+		res: &result{
+			app: &godo.App{ID: "app-id", LiveURL: "https://example.com"},
+			deployment: &godo.Deployment{
+				ID:    "deployment-id",
+				Phase: godo.DeploymentPhase_Active,
+				Progress: &godo.DeploymentProgress{
+					Steps: []*godo.DeploymentProgressStep{{
+						Name:      "build",
+						Status:    godo.DeploymentProgressStepStatus_Success,
+						StartedAt: started,
+						EndedAt:   started.Add(90 * time.Second),
+					}, {
+						Name:   "deploy",
+						Status: godo.DeploymentProgressStepStatus_Pending,
+					}},
+				},
+			},
+		},
+		expected: "## :white_check_mark: Deployment of `foo` succeeded\n\n" +
+			"| | |\n|---|---|\n" +
+			"| App | `foo` (`app-id`) |\n" +
+			"| Deployment | `deployment-id` |\n" +
+			"| Phase | `ACTIVE` |\n" +
+			"| Live URL | https://example.com |\n" +
+			"\n### Steps\n\n| Step | Status | Duration |\n|---|---|---|\n" +
+			"| build | `SUCCESS` | 1m30s |\n" +
+			"| deploy | `PENDING` | - |\n" +
+			"\n### Components\n\n| Component | Type | Source |\n|---|---|---|\n" +
+			"| web | service | image `foo/bar@sha256:123` |\n" +
+			"| worker | worker | GitHub `foo/bar` branch `main` |\n" +
+			"| db | database | - |\n",
+	}, {
+		name: "failure",
+		res: &result{
+			deployment: &godo.Deployment{
+				ID:    "deployment-id",
+				Phase: godo.DeploymentPhase_Error,
+			},
+			buildLogs: []byte("line 1\nline 2\n"),
+		},
+		err: errors.New("deployment failed"),
+		expected: "## :x: Deployment of `foo` failed\n\n" +
+			"| | |\n|---|---|\n" +
+			"| App | `foo` |\n" +
+			"| Deployment | `deployment-id` |\n" +
+			"| Phase | `ERROR` |\n" +
+			"\n### Components\n\n| Component | Type | Source |\n|---|---|---|\n" +
+			"| web | service | image `foo/bar@sha256:123` |\n" +
+			"| worker | worker | GitHub `foo/bar` branch `main` |\n" +
+			"| db | database | - |\n" +
+			"\n### Error\n\n```\ndeployment failed\n```\n" +
+			"\n<details>\n<summary>Last 50 lines of the build logs</summary>\n\n```\nline 1\nline 2\n```\n</details>\n",
+	}, {
+		name: "superseded",
+		res: &result{
+			deployment: &godo.Deployment{
+				ID:    "deployment-id",
+				Phase: godo.DeploymentPhase_Superseded,
+			},
+			buildLogs: []byte("line 1\nline 2\n"),
+		},
+		err: fmt.Errorf("deployment deployment-id was %w", errSuperseded),
+		expected: "## :fast_forward: Deployment of `foo` was superseded\n\n" +
+			"| | |\n|---|---|\n" +
+			"| App | `foo` |\n" +
+			"| Deployment | `deployment-id` |\n" +
+			"| Phase | `SUPERSEDED` |\n" +
+			"\n### Components\n\n| Component | Type | Source |\n|---|---|---|\n" +
+			"| web | service | image `foo/bar@sha256:123` |\n" +
+			"| worker | worker | GitHub `foo/bar` branch `main` |\n" +
+			"| db | database | - |\n",
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			require.Equal(t, test.expected, renderSummary(spec, test.res, test.err))
+		})
+	}
+}
+
+func TestTailLines(t *testing.T) {
+	require.Equal(t, []byte("3\n4"), tailLines([]byte("1\n2\n3\n4\n"), 2))
+	require.Equal(t, []byte("1\n2"), tailLines([]byte("1\n2"), 5))
+}
+
+func TestComponentSource(t *testing.T) {
+	tests := []struct {
+		name      string
+		component godo.AppComponentSpec
+		expected  string
+	}{{
+		name:      "image",
+		component: &godo.AppServiceSpec{Image: &godo.ImageSourceSpec{Registry: "foo", Repository: "bar", Tag: "v1"}},
+		expected:  "image `foo/bar:v1`",
+	}, {
+		name:      "bitbucket",
+		component: &godo.AppWorkerSpec{Bitbucket: &godo.BitbucketSourceSpec{Repo: "foo/bar", Branch: "main"}},
+		expected:  "Bitbucket `foo/bar` branch `main`",
+	}, {
+		name:      "git",
+		component: &godo.AppStaticSiteSpec{Git: &godo.GitSourceSpec{RepoCloneURL: "https://example.com/foo/bar.git", Branch: "main"}},
+		expected:  "Git `https://example.com/foo/bar.git` branch `main`",
+	}, {
+		name:      "none",
+		component: &godo.AppDatabaseSpec{Name: "db"},
+		expected:  "-",
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			require.Equal(t, test.expected, componentSource(test.component))
+		})
+	}
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"github.com/digitalocean/app_action/utils"
+	"github.com/digitalocean/godo"
+)
+
+// changeReason returns why deploying the spec would change the app or an
+// empty string if the app would stay as is.
+// Fields that are only set in the live spec, apart from lists, are assumed to
+// be defaults filled in by App Platform. The values of secrets are encrypted in
+// the live spec, so secrets are only compared by their key, type and scope.
+func (d *deployer) changeReason(app *godo.App, spec *godo.AppSpec) (string, error) {
+	if app.GetInProgressDeployment() != nil || app.GetPendingDeployment() != nil {
+		return "a deployment is in progress", nil
+	}
+	active := app.GetActiveDeployment()
+	if active == nil {
+		return "the app has no active deployment", nil
+	}
+
+	desired, err := asJSONValue(spec)
+	if err != nil {
+		return "", err
+	}
+	live, err := asJSONValue(active.GetSpec())
+	if err != nil {
+		return "", err
+	}
+	if path := specDiff(desired, live, "spec"); path != "" {
+		return fmt.Sprintf("%s changed", path), nil
+	}
+
+	// Git sources are always updated to the latest commit of their branch.
+	err = godo.ForEachAppSpecComponent(spec, func(c godo.AppBuildableComponentSpec) error {
+		src := utils.VCSSourceOf(c)
+		if src == nil {
+			return nil
+		}
+		ci := d.ciContext
+		if ci == nil || !ci.IsSourceRepository(src) || src.Branch != ci.Branch || ci.Commit == "" {
+			return fmt.Errorf("the latest commit of the source of component %s is unknown", c.GetName())
+		}
+		if deployed := deployedCommit(active, c.GetName()); deployed != ci.Commit {
+			return fmt.Errorf("component %s is deployed from commit %q instead of %q", c.GetName(), deployed, ci.Commit)
+		}
+		return nil
+	})
+	if err != nil {
+		return err.Error(), nil
+	}
+	return "", nil
+}
+
+// asJSONValue returns the spec, or a part of it, as a generic JSON value.
+func asJSONValue(spec any) (any, error) {
+	specJSON, err := json.Marshal(spec)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal spec: %w", err)
+	}
+	var v any
+	if err := json.Unmarshal(specJSON, &v); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal spec: %w", err)
+	}
+	return v, nil
+}
+
+// defaultedSpecLists are the lists App Platform fills in if they're not part of
+// the spec.
+var defaultedSpecLists = map[string]bool{
+	"alerts": true,
+}
+
+// specDiff returns the path of the first value of desired that's not equal to
+// the respective value in live or an empty string if there is none. Values
+// only present in live are ignored, apart from lists. Lists must have the
+// same length. The values of secret environment variables are ignored.
+func specDiff(desired, live any, path string) string {
+	switch des := desired.(type) {
+	case map[string]any:
+		l, ok := live.(map[string]any)
+		if !ok {
+			return path
+		}
+		for _, key := range sortedKeys(des, l) {
+			// Secret values are encrypted in the live spec, so we can't tell if they changed.
+			if key == "value" && des["type"] == string(godo.AppVariableType_Secret) {
+				continue
+			}
+			if _, ok := des[key]; !ok {
+				// Lists removed entirely, like all envs of a component, are a
+				// change unless App Platform fills them in by default.
+				if list, ok := l[key].([]any); ok && len(list) > 0 && !defaultedSpecLists[key] {
+					return path + "." + key
+				}
+				continue
+			}
+			if p := specDiff(des[key], l[key], path+"."+key); p != "" {
+				return p
+			}
+		}
+		return ""
+	case []any:
+		l, ok := live.([]any)
+		if !ok || len(des) != len(l) {
+			return path
+		}
+		for i := range des {
+			if p := specDiff(des[i], l[i], fmt.Sprintf("%s[%d]", path, i)); p != "" {
+				return p
+			}
+		}
+		return ""
+	default:
+		if !reflect.DeepEqual(desired, live) {
+			return path
+		}
+		return ""
+	}
+}
+
+// deployedCommit returns the commit the given component was built from in the
+// deployment.
+func deployedCommit(dep *godo.Deployment, component string) string {
+	for _, s := range dep.Services {
+		if s.Name == component {
+			return s.SourceCommitHash
+		}
+	}
+	for _, w := range dep.Workers {
+		if w.Name == component {
+			return w.SourceCommitHash
+		}
+	}
+	for _, j := range dep.Jobs {
+		if j.Name == component {
+			return j.SourceCommitHash
+		}
+	}
+	for _, s := range dep.StaticSites {
+		if s.Name == component {
+			return s.SourceCommitHash
+		}
+	}
+	for _, f := range dep.Functions {
+		if f.Name == component {
+			return f.SourceCommitHash
+		}
+	}
+	return ""
+}
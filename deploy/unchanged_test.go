@@ -0,0 +1,183 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/digitalocean/app_action/utils"
+	"github.com/digitalocean/godo"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChangeReason(t *testing.T) {
+	imageSpec := func() *godo.AppSpec {
+		return &godo.AppSpec{
+			Name: "foo",
+			Services: []*godo.AppServiceSpec{{
+				Name:  "web",
+				Image: &godo.ImageSourceSpec{RegistryType: godo.ImageSourceSpecRegistryType_DOCR, Repository: "web", Tag: "v1"},
+				Envs:  []*godo.AppVariableDefinition{{Key: "FOO", Value: "bar"}},
+			}},
+		}
+	}
+	// liveSpec mimics App Platform filling in defaults.
+	liveSpec := func(spec *godo.AppSpec) *godo.AppSpec {
+		spec.Region = "ams"
+		spec.Services[0].InstanceCount = 1
+		spec.Services[0].Envs[0].Scope = godo.AppVariableScope_RunAndBuildTime
+		return spec
+	}
+	gitSpec := func(branch string) *godo.AppSpec {
+		return &godo.AppSpec{
+			Name: "foo",
+			Workers: []*godo.AppWorkerSpec{{
+				Name:   "worker",
+				GitHub: &godo.GitHubSourceSpec{Repo: "foo/bar", Branch: branch},
+			}},
+		}
+	}
+
+	cloneURLSpec := func(url string) *godo.AppSpec {
+		return &godo.AppSpec{
+			Name: "foo",
+			Workers: []*godo.AppWorkerSpec{{
+				Name: "worker",
+				Git:  &godo.GitSourceSpec{RepoCloneURL: url, Branch: "main"},
+			}},
+		}
+	}
+	bitbucketSpec := func() *godo.AppSpec {
+		return &godo.AppSpec{
+			Name: "foo",
+			Workers: []*godo.AppWorkerSpec{{
+				Name:      "worker",
+				Bitbucket: &godo.BitbucketSourceSpec{Repo: "foo/bar", Branch: "main"},
+			}},
+		}
+	}
+
+	tests := []struct {
+		name     string
+		app      *godo.App
+		spec     *godo.AppSpec
+		expected string
+	}{{
+		name: "unchanged image",
+		app: &godo.App{ActiveDeployment: &godo.Deployment{
+			Spec: liveSpec(imageSpec()),
+		}},
+		spec: imageSpec(),
+	}, {
+		name: "changed tag",
+		app: &godo.App{ActiveDeployment: &godo.Deployment{
+			Spec: liveSpec(imageSpec()),
+		}},
+		spec: func() *godo.AppSpec {
+			spec := imageSpec()
+			spec.Services[0].Image.Tag = "v2"
+			return spec
+		}(),
+		expected: "spec.services[0].image.tag changed",
+	}, {
+		name: "removed env",
+		app: &godo.App{ActiveDeployment: &godo.Deployment{
+			Spec: liveSpec(imageSpec()),
+		}},
+		spec: func() *godo.AppSpec {
+			spec := imageSpec()
+			spec.Services[0].Envs = nil
+			return spec
+		}(),
+		expected: "spec.services[0].envs changed",
+	}, {
+		name: "unchanged secret",
+		app: &godo.App{ActiveDeployment: &godo.Deployment{
+			Spec: func() *godo.AppSpec {
+				spec := liveSpec(imageSpec())
+				spec.Services[0].Envs[0].Type = godo.AppVariableType_Secret
+				spec.Services[0].Envs[0].Value = "EV[1:abc:def]"
+				return spec
+			}(),
+		}},
+		spec: func() *godo.AppSpec {
+			spec := imageSpec()
+			spec.Services[0].Envs[0].Type = godo.AppVariableType_Secret
+			return spec
+		}(),
+	}, {
+		name: "secret turned into a general env",
+		app: &godo.App{ActiveDeployment: &godo.Deployment{
+			Spec: func() *godo.AppSpec {
+				spec := liveSpec(imageSpec())
+				spec.Services[0].Envs[0].Type = godo.AppVariableType_Secret
+				spec.Services[0].Envs[0].Value = "EV[1:abc:def]"
+				return spec
+			}(),
+		}},
+		spec: func() *godo.AppSpec {
+			spec := imageSpec()
+			spec.Services[0].Envs[0].Type = godo.AppVariableType_General
+			return spec
+		}(),
+		expected: "spec.services[0].envs[0].type changed",
+	}, {
+		name:     "no active deployment",
+		app:      &godo.App{},
+		spec:     imageSpec(),
+		expected: "the app has no active deployment",
+	}, {
+		name: "deployment in progress",
+		app: &godo.App{
+			ActiveDeployment:     &godo.Deployment{Spec: liveSpec(imageSpec())},
+			InProgressDeployment: &godo.Deployment{},
+		},
+		spec:     imageSpec(),
+		expected: "a deployment is in progress",
+	}, {
+		name: "unchanged commit",
+		app: &godo.App{ActiveDeployment: &godo.Deployment{
+			Spec:    gitSpec("main"),
+			Workers: []*godo.DeploymentWorker{{Name: "worker", SourceCommitHash: "abc"}},
+		}},
+		spec: gitSpec("main"),
+	}, {
+		name: "changed commit",
+		app: &godo.App{ActiveDeployment: &godo.Deployment{
+			Spec:    gitSpec("main"),
+			Workers: []*godo.DeploymentWorker{{Name: "worker", SourceCommitHash: "old"}},
+		}},
+		spec:     gitSpec("main"),
+		expected: `component worker is deployed from commit "old" instead of "abc"`,
+	}, {
+		name: "unknown commit",
+		app: &godo.App{ActiveDeployment: &godo.Deployment{
+			Spec:    gitSpec("other"),
+			Workers: []*godo.DeploymentWorker{{Name: "worker", SourceCommitHash: "abc"}},
+		}},
+		spec:     gitSpec("other"),
+		expected: "the latest commit of the source of component worker is unknown",
+	}, {
+		name: "unchanged git commit",
+		app: &godo.App{ActiveDeployment: &godo.Deployment{
+			Spec:    cloneURLSpec("https://github.com/foo/bar.git"),
+			Workers: []*godo.DeploymentWorker{{Name: "worker", SourceCommitHash: "abc"}},
+		}},
+		spec: cloneURLSpec("https://github.com/foo/bar.git"),
+	}, {
+		name: "changed bitbucket commit",
+		app: &godo.App{ActiveDeployment: &godo.Deployment{
+			Spec:    bitbucketSpec(),
+			Workers: []*godo.DeploymentWorker{{Name: "worker", SourceCommitHash: "old"}},
+		}},
+		spec:     bitbucketSpec(),
+		expected: `component worker is deployed from commit "old" instead of "abc"`,
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			d := &deployer{ciContext: &utils.CIContext{Repository: "foo/bar", Branch: "main", Commit: "abc"}}
+			reason, err := d.changeReason(test.app, test.spec)
+			require.NoError(t, err)
+			require.Equal(t, test.expected, reason)
+		})
+	}
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/digitalocean/godo"
+)
+
+// validateSpec validates the spec server-side via the Propose API before it's
+// applied and surfaces the app's estimated monthly cost and its delta to the
+// current app as outputs.
+// app is the existing app or nil if the app is yet to be created.
+func (d *deployer) validateSpec(ctx context.Context, spec *godo.AppSpec, app *godo.App) error {
+	proposal, _, err := d.apps.Propose(ctx, &godo.AppProposeRequest{Spec: spec, AppID: app.GetID()})
+	if err != nil {
+		var errResp *godo.ErrorResponse
+		if errors.As(err, &errResp) && errResp.Response != nil && errResp.Response.StatusCode == http.StatusBadRequest {
+			// The message contains the field-level validation errors.
+			return fmt.Errorf("app spec is invalid: %s", errResp.Message)
+		}
+		return fmt.Errorf("failed to validate app spec: %w", err)
+	}
+	if app == nil && !proposal.AppNameAvailable && proposal.AppNameSuggestion != "" {
+		return fmt.Errorf("app name %q is not available, consider using %q instead", spec.GetName(), proposal.AppNameSuggestion)
+	}
+
+	cost := proposal.AppCost
+	d.action.SetOutput("cost_estimate", fmt.Sprintf("%.2f", cost))
+	if app == nil {
+		d.action.Infof("app spec is valid, estimated monthly cost: $%.2f", cost)
+		d.action.SetOutput("cost_delta", fmt.Sprintf("%.2f", cost))
+		return nil
+	}
+
+	// Propose the current spec as well to get the cost of the app as it is now.
+	current, _, err := d.apps.Propose(ctx, &godo.AppProposeRequest{Spec: app.GetSpec(), AppID: app.GetID()})
+	if err != nil {
+		d.action.Warningf("failed to estimate the cost of the current app: %v", err)
+		d.action.Infof("app spec is valid, estimated monthly cost: $%.2f", cost)
+		return nil
+	}
+	delta := cost - current.AppCost
+	d.action.Infof("app spec is valid, estimated monthly cost: $%.2f (%+.2f compared to the current app)", cost, delta)
+	d.action.SetOutput("cost_delta", fmt.Sprintf("%.2f", delta))
+	return nil
+}
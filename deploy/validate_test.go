@@ -0,0 +1,136 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"testing"
+
+	"github.com/digitalocean/godo"
+	gha "github.com/sethvargo/go-githubactions"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateSpec(t *testing.T) {
+	ctx := context.Background()
+	spec := &godo.AppSpec{Name: "foo"}
+	currentSpec := &godo.AppSpec{Name: "foo", Region: "ams"}
+	existingApp := &godo.App{ID: "app-id", Spec: currentSpec}
+
+	tests := []struct {
+		name           string
+		app            *godo.App
+		appService     *mockedAppsService
+		expectedErr    string
+		expectedLogs   []byte
+		expectedOutput []byte
+	}{{
+		name: "new app",
+		appService: func() *mockedAppsService {
+			as := &mockedAppsService{}
+			as.On("Propose", ctx, &godo.AppProposeRequest{Spec: spec}).Return(&godo.AppProposeResponse{
+				AppNameAvailable: true,
+				AppCost:          12,
+			}, &godo.Response{}, nil)
+			return as
+		}(),
+		expectedLogs: []byte(`app spec is valid, estimated monthly cost: $12.00
+`),
+		expectedOutput: []byte(`cost_estimate<<_GitHubActionsFileCommandDelimeter_
+12.00
+_GitHubActionsFileCommandDelimeter_
+cost_delta<<_GitHubActionsFileCommandDelimeter_
+12.00
+_GitHubActionsFileCommandDelimeter_
+`),
+	}, {
+		name: "existing app",
+		app:  existingApp,
+		appService: func() *mockedAppsService {
+			as := &mockedAppsService{}
+			as.On("Propose", ctx, &godo.AppProposeRequest{Spec: spec, AppID: "app-id"}).Return(&godo.AppProposeResponse{
+				AppCost: 12,
+			}, &godo.Response{}, nil)
+			as.On("Propose", ctx, &godo.AppProposeRequest{Spec: currentSpec, AppID: "app-id"}).Return(&godo.AppProposeResponse{
+				AppCost: 17,
+			}, &godo.Response{}, nil)
+			return as
+		}(),
+		expectedLogs: []byte(`app spec is valid, estimated monthly cost: $12.00 (-5.00 compared to the current app)
+`),
+		expectedOutput: []byte(`cost_estimate<<_GitHubActionsFileCommandDelimeter_
+12.00
+_GitHubActionsFileCommandDelimeter_
+cost_delta<<_GitHubActionsFileCommandDelimeter_
+-5.00
+_GitHubActionsFileCommandDelimeter_
+`),
+	}, {
+		name: "invalid spec",
+		appService: func() *mockedAppsService {
+			as := &mockedAppsService{}
+			as.On("Propose", ctx, mock.Anything).Return(&godo.AppProposeResponse{}, &godo.Response{}, &godo.ErrorResponse{
+				Response: &http.Response{StatusCode: http.StatusBadRequest},
+				Message:  `error validating app spec field "services.name": must be unique`,
+			})
+			return as
+		}(),
+		expectedErr: `app spec is invalid: error validating app spec field "services.name": must be unique`,
+	}, {
+		name: "name not available",
+		appService: func() *mockedAppsService {
+			as := &mockedAppsService{}
+			as.On("Propose", ctx, mock.Anything).Return(&godo.AppProposeResponse{
+				AppNameSuggestion: "foo-2",
+			}, &godo.Response{}, nil)
+			return as
+		}(),
+		expectedErr: `app name "foo" is not available, consider using "foo-2" instead`,
+	}, {
+		name: "fails to propose",
+		appService: func() *mockedAppsService {
+			as := &mockedAppsService{}
+			as.On("Propose", ctx, mock.Anything).Return(&godo.AppProposeResponse{}, &godo.Response{}, errors.New("an error"))
+			return as
+		}(),
+		expectedErr: "failed to validate app spec: an error",
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var actionLogs bytes.Buffer
+			outputFilePath := t.TempDir() + "/output"
+			d := &deployer{
+				action: gha.New(gha.WithWriter(&actionLogs), gha.WithGetenv(func(k string) string {
+					switch k {
+					case "GITHUB_OUTPUT":
+						return outputFilePath
+					default:
+						return ""
+					}
+				})),
+				apps: test.appService,
+			}
+			err := d.validateSpec(ctx, spec, test.app)
+			if test.expectedErr != "" {
+				require.EqualError(t, err, test.expectedErr)
+			} else {
+				require.NoError(t, err)
+			}
+			require.Equal(t, test.expectedLogs, actionLogs.Bytes())
+
+			output, err := os.ReadFile(outputFilePath)
+			if test.expectedOutput == nil {
+				require.ErrorIs(t, err, os.ErrNotExist)
+			} else {
+				require.NoError(t, err)
+				require.Equal(t, test.expectedOutput, output)
+			}
+
+			test.appService.AssertExpectations(t)
+		})
+	}
+}
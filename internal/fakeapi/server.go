@@ -0,0 +1,784 @@
+// Package fakeapi implements an in-memory fake of the App Platform API. It
+// allows running the actions end-to-end without network access.
+package fakeapi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/digitalocean/godo"
+)
+
+const (
+	// defaultPerPage is the page size if a list request doesn't specify one.
+	defaultPerPage = 20
+	// maxPerPage is the maximum page size a list request may ask for.
+	maxPerPage = 200
+	// componentCost is the monthly cost of a single component in proposals.
+	componentCost = 5.0
+)
+
+// instanceSizes are the instance sizes served by the fake, a subset of App
+// Platform's.
+var instanceSizes = []*godo.AppInstanceSize{
+	{Slug: "apps-s-1vcpu-0.5gb", TierSlug: "basic", USDPerMonth: "5.00", SingleInstanceOnly: true},
+	{Slug: "apps-s-1vcpu-1gb", TierSlug: "basic", USDPerMonth: "12.00"},
+	{Slug: "apps-d-1vcpu-0.5gb", TierSlug: "professional", USDPerMonth: "29.00", Scalable: true},
+	{Slug: "apps-d-2vcpu-4gb", TierSlug: "professional", USDPerMonth: "78.00", Scalable: true},
+}
+
+// deploymentPhases are the phases a successful deployment progresses through.
+var deploymentPhases = []godo.DeploymentPhase{
+	godo.DeploymentPhase_PendingBuild,
+	godo.DeploymentPhase_Building,
+	godo.DeploymentPhase_PendingDeploy,
+	godo.DeploymentPhase_Deploying,
+	godo.DeploymentPhase_Active,
+}
+
+// appNameRegexp matches valid app names.
+var appNameRegexp = regexp.MustCompile(`^[a-z][a-z0-9-]{0,30}[a-z0-9]$`)
+
+// Option configures a Server.
+type Option func(*Server)
+
+// WithClock sets the clock deployments progress on. Defaults to time.Now.
+func WithClock(clock func() time.Time) Option {
+	return func(s *Server) {
+		s.clock = clock
+	}
+}
+
+// WithPhaseDuration sets the time a deployment spends in each phase. By
+// default, deployments finish immediately.
+func WithPhaseDuration(d time.Duration) Option {
+	return func(s *Server) {
+		s.phaseDuration = d
+	}
+}
+
+// Server is a fake App Platform API. Its URL is meant to be used as the API
+// base URL of a godo client.
+type Server struct {
+	// URL is the base URL of the server.
+	URL string
+
+	srv           *httptest.Server
+	clock         func() time.Time
+	phaseDuration time.Duration
+
+	mu      sync.Mutex
+	apps    []*app
+	failing map[string]bool
+	ids     map[string]int
+}
+
+// app is an app and its deployments, newest first.
+type app struct {
+	app         *godo.App
+	deployments []*deployment
+}
+
+// deployment is a deployment and the state needed to progress it.
+type deployment struct {
+	deployment *godo.Deployment
+	fail       bool
+	// final is the phase the deployment ended in, if it ended.
+	final godo.DeploymentPhase
+}
+
+// New starts a new fake API server. It must be closed once done.
+func New(opts ...Option) *Server {
+	s := &Server{
+		clock:   time.Now,
+		failing: make(map[string]bool),
+		ids:     make(map[string]int),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v2/apps", s.listApps)
+	mux.HandleFunc("POST /v2/apps", s.createApp)
+	mux.HandleFunc("POST /v2/apps/propose", s.propose)
+	mux.HandleFunc("GET /v2/apps/tiers/instance_sizes", s.listInstanceSizes)
+	mux.HandleFunc("GET /v2/apps/{app}", s.getApp)
+	mux.HandleFunc("PUT /v2/apps/{app}", s.updateApp)
+	mux.HandleFunc("DELETE /v2/apps/{app}", s.deleteApp)
+	mux.HandleFunc("POST /v2/apps/{app}/rollback", s.rollback)
+	mux.HandleFunc("GET /v2/apps/{app}/deployments", s.listDeployments)
+	mux.HandleFunc("POST /v2/apps/{app}/deployments", s.createDeployment)
+	mux.HandleFunc("GET /v2/apps/{app}/deployments/{deployment}", s.getDeployment)
+	mux.HandleFunc("POST /v2/apps/{app}/deployments/{deployment}/cancel", s.cancelDeployment)
+	mux.HandleFunc("GET /v2/apps/{app}/deployments/{deployment}/logs", s.getLogs)
+	mux.HandleFunc("GET /logs/{deployment}/{type}", s.serveLogs)
+	mux.HandleFunc("GET /live/{app}", s.serveLive)
+	mux.HandleFunc("GET /live/{app}/", s.serveLive)
+	mux.HandleFunc("/", func(w http.ResponseWriter, _ *http.Request) {
+		writeError(w, http.StatusNotFound, "not_found", "the resource you requested could not be found")
+	})
+
+	s.srv = httptest.NewServer(mux)
+	s.URL = s.srv.URL
+	return s
+}
+
+// Close shuts the server down.
+func (s *Server) Close() {
+	s.srv.Close()
+}
+
+// FailDeployments makes all future deployments of the app with the given name
+// fail while deploying.
+func (s *Server) FailDeployments(appName string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failing[appName] = true
+}
+
+// Apps returns the current state of all apps.
+func (s *Server) Apps() []*godo.App {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	apps := make([]*godo.App, 0, len(s.apps))
+	for _, a := range s.apps {
+		s.refresh(a)
+		apps = append(apps, a.app)
+	}
+	return apps
+}
+
+// Deployments returns the current state of all deployments of the app with
+// the given ID, newest first.
+func (s *Server) Deployments(appID string) []*godo.Deployment {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	a := s.findApp(appID)
+	if a == nil {
+		return nil
+	}
+	s.refresh(a)
+	deployments := make([]*godo.Deployment, 0, len(a.deployments))
+	for _, d := range a.deployments {
+		deployments = append(deployments, d.deployment)
+	}
+	return deployments
+}
+
+func (s *Server) listApps(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	start, end, links, err := s.paginate(r, len(s.apps))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "bad_request", err.Error())
+		return
+	}
+	apps := make([]*godo.App, 0, end-start)
+	for _, a := range s.apps[start:end] {
+		s.refresh(a)
+		apps = append(apps, a.app)
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"apps":  apps,
+		"links": links,
+		"meta":  &godo.Meta{Total: len(s.apps)},
+	})
+}
+
+func (s *Server) createApp(w http.ResponseWriter, r *http.Request) {
+	var req godo.AppCreateRequest
+	if !readJSON(w, r, &req) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := validateSpec(req.Spec); err != nil {
+		writeError(w, http.StatusBadRequest, "bad_request", err.Error())
+		return
+	}
+	if s.findAppByName(req.Spec.Name) != nil {
+		writeError(w, http.StatusConflict, "conflict", fmt.Sprintf("an app with the name %q already exists", req.Spec.Name))
+		return
+	}
+
+	now := s.clock()
+	a := &app{app: &godo.App{
+		ID:        s.newID("app"),
+		Spec:      req.Spec,
+		ProjectID: req.ProjectID,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}}
+	s.apps = append(s.apps, a)
+	s.deploy(a, godo.DeploymentCauseDetailsType_Unknown)
+	writeJSON(w, http.StatusOK, map[string]any{"app": a.app})
+}
+
+func (s *Server) propose(w http.ResponseWriter, r *http.Request) {
+	var req godo.AppProposeRequest
+	if !readJSON(w, r, &req) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := validateSpec(req.Spec); err != nil {
+		writeError(w, http.StatusBadRequest, "bad_request", err.Error())
+		return
+	}
+	resp := &godo.AppProposeResponse{
+		AppNameAvailable: true,
+		AppCost:          float32(componentCost * float64(countComponents(req.Spec))),
+	}
+	if existing := s.findAppByName(req.Spec.Name); existing != nil && existing.app.ID != req.AppID {
+		resp.AppNameAvailable = false
+		resp.AppNameSuggestion = req.Spec.Name + "-2"
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (s *Server) listInstanceSizes(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]any{"instance_sizes": instanceSizes})
+}
+
+func (s *Server) getApp(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	a, ok := s.appFromRequest(w, r)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"app": a.app})
+}
+
+func (s *Server) updateApp(w http.ResponseWriter, r *http.Request) {
+	var req godo.AppUpdateRequest
+	if !readJSON(w, r, &req) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	a, ok := s.appFromRequest(w, r)
+	if !ok {
+		return
+	}
+	if err := validateSpec(req.Spec); err != nil {
+		writeError(w, http.StatusBadRequest, "bad_request", err.Error())
+		return
+	}
+	if existing := s.findAppByName(req.Spec.Name); existing != nil && existing != a {
+		writeError(w, http.StatusConflict, "conflict", fmt.Sprintf("an app with the name %q already exists", req.Spec.Name))
+		return
+	}
+
+	a.app.Spec = req.Spec
+	a.app.UpdatedAt = s.clock()
+	s.deploy(a, godo.DeploymentCauseDetailsType_Manual)
+	writeJSON(w, http.StatusOK, map[string]any{"app": a.app})
+}
+
+func (s *Server) deleteApp(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	a, ok := s.appFromRequest(w, r)
+	if !ok {
+		return
+	}
+	for i := range s.apps {
+		if s.apps[i] == a {
+			s.apps = append(s.apps[:i], s.apps[i+1:]...)
+			break
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"id": a.app.ID})
+}
+
+func (s *Server) rollback(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		DeploymentID string `json:"deployment_id"`
+	}
+	if !readJSON(w, r, &req) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	a, ok := s.appFromRequest(w, r)
+	if !ok {
+		return
+	}
+	target := findDeployment(a, req.DeploymentID)
+	if target == nil {
+		writeError(w, http.StatusNotFound, "not_found", fmt.Sprintf("deployment %s not found", req.DeploymentID))
+		return
+	}
+	if target.deployment.Phase != godo.DeploymentPhase_Active {
+		writeError(w, http.StatusBadRequest, "bad_request", "can only roll back to a deployment that was active")
+		return
+	}
+
+	a.app.Spec = target.deployment.Spec
+	a.app.UpdatedAt = s.clock()
+	d := s.deploy(a, godo.DeploymentCauseDetailsType_ManualRollback)
+	writeJSON(w, http.StatusOK, map[string]any{"deployment": d.deployment})
+}
+
+func (s *Server) listDeployments(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	a, ok := s.appFromRequest(w, r)
+	if !ok {
+		return
+	}
+	start, end, links, err := s.paginate(r, len(a.deployments))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "bad_request", err.Error())
+		return
+	}
+	deployments := make([]*godo.Deployment, 0, end-start)
+	for _, d := range a.deployments[start:end] {
+		deployments = append(deployments, d.deployment)
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"deployments": deployments,
+		"links":       links,
+		"meta":        &godo.Meta{Total: len(a.deployments)},
+	})
+}
+
+func (s *Server) createDeployment(w http.ResponseWriter, r *http.Request) {
+	var req godo.DeploymentCreateRequest
+	if !readJSON(w, r, &req) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	a, ok := s.appFromRequest(w, r)
+	if !ok {
+		return
+	}
+	d := s.deploy(a, godo.DeploymentCauseDetailsType_Manual)
+	writeJSON(w, http.StatusOK, map[string]any{"deployment": d.deployment})
+}
+
+func (s *Server) getDeployment(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, d, ok := s.deploymentFromRequest(w, r)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"deployment": d.deployment})
+}
+
+func (s *Server) cancelDeployment(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	a, d, ok := s.deploymentFromRequest(w, r)
+	if !ok {
+		return
+	}
+	if d.final != "" {
+		writeError(w, http.StatusBadRequest, "bad_request", "the deployment is not in progress")
+		return
+	}
+	d.final = godo.DeploymentPhase_Canceled
+	s.refresh(a)
+	writeJSON(w, http.StatusOK, map[string]any{"deployment": d.deployment})
+}
+
+func (s *Server) getLogs(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, d, ok := s.deploymentFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	logType := godo.AppLogType(r.URL.Query().Get("type"))
+	var logPhase godo.DeploymentPhase
+	switch logType {
+	case godo.AppLogTypeBuild:
+		logPhase = godo.DeploymentPhase_Building
+	case godo.AppLogTypeDeploy:
+		logPhase = godo.DeploymentPhase_Deploying
+	default:
+		writeError(w, http.StatusBadRequest, "bad_request", fmt.Sprintf("unsupported log type %q", logType))
+		return
+	}
+
+	reached := d.reachedPhase()
+	switch {
+	case phaseIndex(reached) < phaseIndex(logPhase):
+		writeError(w, http.StatusBadRequest, "bad_request", fmt.Sprintf("deployment has no %s logs", logType))
+	case reached == logPhase && d.final == "":
+		writeJSON(w, http.StatusOK, &godo.AppLogs{LiveURL: s.logsURL(d, logType)})
+	default:
+		writeJSON(w, http.StatusOK, &godo.AppLogs{HistoricURLs: []string{s.logsURL(d, logType)}})
+	}
+}
+
+func (s *Server) serveLogs(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, a := range s.apps {
+		d := findDeployment(a, r.PathValue("deployment"))
+		if d == nil {
+			continue
+		}
+		w.Header().Set("Content-Type", "text/plain")
+		_ = godo.ForEachAppSpecComponent(d.deployment.Spec, func(c godo.AppComponentSpec) error {
+			fmt.Fprintf(w, "%s %s: done\n", r.PathValue("type"), c.GetName())
+			return nil
+		})
+		return
+	}
+	http.NotFound(w, r)
+}
+
+func (s *Server) serveLive(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	a := s.findApp(r.PathValue("app"))
+	if a == nil {
+		http.NotFound(w, r)
+		return
+	}
+	s.refresh(a)
+	if a.app.ActiveDeployment == nil {
+		http.Error(w, "no active deployment", http.StatusServiceUnavailable)
+		return
+	}
+	fmt.Fprintf(w, "%s is live\n", a.app.Spec.Name)
+}
+
+// deploy starts a new deployment of the app's current spec, superseding all
+// deployments in progress.
+func (s *Server) deploy(a *app, cause godo.DeploymentCauseDetailsType) *deployment {
+	for _, d := range a.deployments {
+		if d.final == "" {
+			d.final = godo.DeploymentPhase_Superseded
+		}
+	}
+
+	now := s.clock()
+	d := &deployment{
+		deployment: &godo.Deployment{
+			ID:        s.newID("deployment"),
+			Spec:      a.app.Spec,
+			Cause:     string(cause),
+			CreatedAt: now,
+			UpdatedAt: now,
+		},
+		fail: s.failing[a.app.Spec.Name],
+	}
+	a.deployments = append([]*deployment{d}, a.deployments...)
+	s.refresh(a)
+	return d
+}
+
+// refresh progresses the app's deployments according to the clock and
+// updates the app accordingly.
+func (s *Server) refresh(a *app) {
+	now := s.clock()
+	a.app.ActiveDeployment = nil
+	a.app.InProgressDeployment = nil
+	for i := len(a.deployments) - 1; i >= 0; i-- {
+		d := a.deployments[i]
+		s.progress(d, now)
+		switch d.deployment.Phase {
+		case godo.DeploymentPhase_Active:
+			a.app.ActiveDeployment = d.deployment
+		case godo.DeploymentPhase_Error, godo.DeploymentPhase_Canceled, godo.DeploymentPhase_Superseded:
+		default:
+			a.app.InProgressDeployment = d.deployment
+		}
+	}
+
+	a.app.LiveURL = ""
+	a.app.DefaultIngress = ""
+	if a.app.ActiveDeployment != nil {
+		a.app.LiveURL = fmt.Sprintf("%s/live/%s", s.URL, a.app.ID)
+		a.app.DefaultIngress = a.app.LiveURL
+		a.app.LastDeploymentActiveAt = a.app.ActiveDeployment.UpdatedAt
+	}
+}
+
+// progress moves the deployment to the phase it's in at the given time.
+func (s *Server) progress(d *deployment, now time.Time) {
+	if d.final == "" {
+		i := len(deploymentPhases) - 1
+		if s.phaseDuration > 0 {
+			i = min(i, int(now.Sub(d.deployment.CreatedAt)/s.phaseDuration))
+		}
+		switch phase := deploymentPhases[i]; {
+		case phase == godo.DeploymentPhase_Active && d.fail:
+			d.final = godo.DeploymentPhase_Error
+		case phase == godo.DeploymentPhase_Active:
+			d.final = godo.DeploymentPhase_Active
+		}
+		if d.final == "" && d.deployment.Phase != deploymentPhases[i] {
+			d.deployment.Phase = deploymentPhases[i]
+			d.deployment.PhaseLastUpdatedAt = now
+			d.deployment.UpdatedAt = now
+		}
+	}
+	if d.final != "" && d.deployment.Phase != d.final {
+		d.deployment.Phase = d.final
+		d.deployment.PhaseLastUpdatedAt = now
+		d.deployment.UpdatedAt = now
+	}
+	d.deployment.Progress = progressOf(d)
+}
+
+// reachedPhase returns the furthest phase of a successful deployment the
+// deployment reached.
+func (d *deployment) reachedPhase() godo.DeploymentPhase {
+	switch d.deployment.Phase {
+	case godo.DeploymentPhase_Error:
+		// Failing deployments fail while deploying.
+		return godo.DeploymentPhase_Deploying
+	case godo.DeploymentPhase_Canceled, godo.DeploymentPhase_Superseded:
+		// It's unknown how far they got, so assume they never started.
+		return godo.DeploymentPhase_PendingBuild
+	}
+	return d.deployment.Phase
+}
+
+// progressOf returns the progress steps of the deployment's current phase.
+func progressOf(d *deployment) *godo.DeploymentProgress {
+	reached := phaseIndex(d.reachedPhase())
+	stepStatus := func(phase godo.DeploymentPhase) godo.DeploymentProgressStepStatus {
+		switch i := phaseIndex(phase); {
+		case i < reached:
+			return godo.DeploymentProgressStepStatus_Success
+		case i == reached && d.deployment.Phase == godo.DeploymentPhase_Error:
+			return godo.DeploymentProgressStepStatus_Error
+		case i == reached:
+			return godo.DeploymentProgressStepStatus_Running
+		}
+		return godo.DeploymentProgressStepStatus_Pending
+	}
+
+	steps := []*godo.DeploymentProgressStep{
+		{Name: "build", Status: stepStatus(godo.DeploymentPhase_Building)},
+		{Name: "deploy", Status: stepStatus(godo.DeploymentPhase_Deploying)},
+	}
+	if d.deployment.Phase == godo.DeploymentPhase_Active {
+		for _, step := range steps {
+			step.Status = godo.DeploymentProgressStepStatus_Success
+		}
+	}
+	progress := &godo.DeploymentProgress{Steps: steps, TotalSteps: int32(len(steps))}
+	for _, step := range steps {
+		switch step.Status {
+		case godo.DeploymentProgressStepStatus_Success:
+			progress.SuccessSteps++
+		case godo.DeploymentProgressStepStatus_Running:
+			progress.RunningSteps++
+		case godo.DeploymentProgressStepStatus_Error:
+			progress.ErrorSteps++
+			step.Reason = &godo.DeploymentProgressStepReason{
+				Code:    "DeployContainerHealthChecksFailed",
+				Message: "your deploy failed because your container failed to pass health checks",
+			}
+		default:
+			progress.PendingSteps++
+		}
+	}
+	return progress
+}
+
+// phaseIndex returns the position of the phase in a successful deployment.
+func phaseIndex(phase godo.DeploymentPhase) int {
+	for i, p := range deploymentPhases {
+		if p == phase {
+			return i
+		}
+	}
+	return -1
+}
+
+// appFromRequest returns the app the request's path refers to. If it
+// doesn't exist, it writes a 404 response.
+func (s *Server) appFromRequest(w http.ResponseWriter, r *http.Request) (*app, bool) {
+	a := s.findApp(r.PathValue("app"))
+	if a == nil {
+		writeError(w, http.StatusNotFound, "not_found", fmt.Sprintf("app %s not found", r.PathValue("app")))
+		return nil, false
+	}
+	s.refresh(a)
+	return a, true
+}
+
+// deploymentFromRequest returns the app and deployment the request's path
+// refers to. If either doesn't exist, it writes a 404 response.
+func (s *Server) deploymentFromRequest(w http.ResponseWriter, r *http.Request) (*app, *deployment, bool) {
+	a, ok := s.appFromRequest(w, r)
+	if !ok {
+		return nil, nil, false
+	}
+	d := findDeployment(a, r.PathValue("deployment"))
+	if d == nil {
+		writeError(w, http.StatusNotFound, "not_found", fmt.Sprintf("deployment %s not found", r.PathValue("deployment")))
+		return nil, nil, false
+	}
+	return a, d, true
+}
+
+func (s *Server) findApp(id string) *app {
+	for _, a := range s.apps {
+		if a.app.ID == id {
+			return a
+		}
+	}
+	return nil
+}
+
+func (s *Server) findAppByName(name string) *app {
+	for _, a := range s.apps {
+		if a.app.Spec.Name == name {
+			return a
+		}
+	}
+	return nil
+}
+
+func findDeployment(a *app, id string) *deployment {
+	for _, d := range a.deployments {
+		if d.deployment.ID == id {
+			return d
+		}
+	}
+	return nil
+}
+
+// newID returns a new unique ID with the given prefix.
+func (s *Server) newID(prefix string) string {
+	s.ids[prefix]++
+	return fmt.Sprintf("%s-%d", prefix, s.ids[prefix])
+}
+
+// logsURL returns the URL the logs of the given type of the deployment are
+// served on.
+func (s *Server) logsURL(d *deployment, logType godo.AppLogType) string {
+	return fmt.Sprintf("%s/logs/%s/%s", s.URL, d.deployment.ID, logType)
+}
+
+// paginate returns the range of the n items to return for the request's page
+// and the links to the other pages.
+func (s *Server) paginate(r *http.Request, n int) (int, int, *godo.Links, error) {
+	page, perPage := 1, defaultPerPage
+	if v := r.URL.Query().Get("page"); v != "" {
+		p, err := strconv.Atoi(v)
+		if err != nil || p < 1 {
+			return 0, 0, nil, fmt.Errorf("invalid page %q", v)
+		}
+		page = p
+	}
+	if v := r.URL.Query().Get("per_page"); v != "" {
+		p, err := strconv.Atoi(v)
+		if err != nil || p < 1 {
+			return 0, 0, nil, fmt.Errorf("invalid per_page %q", v)
+		}
+		perPage = min(p, maxPerPage)
+	}
+
+	lastPage := max(1, (n+perPage-1)/perPage)
+	pageURL := func(p int) string {
+		u := *r.URL
+		q := u.Query()
+		q.Set("page", strconv.Itoa(p))
+		q.Set("per_page", strconv.Itoa(perPage))
+		u.RawQuery = q.Encode()
+		return s.URL + u.RequestURI()
+	}
+	links := &godo.Links{Pages: &godo.Pages{}}
+	if page > 1 {
+		links.Pages.First = pageURL(1)
+		links.Pages.Prev = pageURL(page - 1)
+	}
+	if page < lastPage {
+		links.Pages.Next = pageURL(page + 1)
+		links.Pages.Last = pageURL(lastPage)
+	}
+
+	start := min(n, (page-1)*perPage)
+	end := min(n, start+perPage)
+	return start, end, links, nil
+}
+
+// validateSpec returns an error if the spec would be rejected by App Platform.
+func validateSpec(spec *godo.AppSpec) error {
+	if spec == nil {
+		return fmt.Errorf("spec is required")
+	}
+	if !appNameRegexp.MatchString(spec.Name) {
+		return fmt.Errorf("spec.name %q is invalid: it must be 2 to 32 lowercase alphanumeric characters or dashes, starting with a letter", spec.Name)
+	}
+	if countComponents(spec) == 0 {
+		return fmt.Errorf("spec must contain at least one component")
+	}
+	return nil
+}
+
+// countComponents returns the amount of components in the spec.
+func countComponents(spec *godo.AppSpec) int {
+	var n int
+	_ = godo.ForEachAppSpecComponent(spec, func(godo.AppComponentSpec) error {
+		n++
+		return nil
+	})
+	return n
+}
+
+// readJSON decodes the request's body into v. If that fails, it writes a 400
+// response and returns false.
+func readJSON(w http.ResponseWriter, r *http.Request, v any) bool {
+	// Some requests, like creating a deployment, have an optional body.
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil && !errors.Is(err, io.EOF) {
+		writeError(w, http.StatusBadRequest, "bad_request", fmt.Sprintf("failed to parse request body: %v", err))
+		return false
+	}
+	return true
+}
+
+// writeJSON writes v as the JSON response.
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// writeError writes an error response the way the API does.
+func writeError(w http.ResponseWriter, status int, id, message string) {
+	writeJSON(w, status, map[string]string{"id": id, "message": message})
+}
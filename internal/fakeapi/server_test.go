@@ -0,0 +1,229 @@
+package fakeapi
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/digitalocean/app_action/utils"
+	"github.com/digitalocean/godo"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeClock is a clock that only moves when told to.
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func newClient(t *testing.T, srv *Server) *godo.Client {
+	client, err := utils.NewClient("token", "test", utils.ClientConfig{BaseURL: srv.URL})
+	require.NoError(t, err)
+	return client
+}
+
+func testSpec(name string) *godo.AppSpec {
+	return &godo.AppSpec{
+		Name: name,
+		Services: []*godo.AppServiceSpec{{
+			Name:  "web",
+			Image: &godo.ImageSourceSpec{RegistryType: godo.ImageSourceSpecRegistryType_DOCR, Repository: "web", Tag: "v1"},
+		}},
+	}
+}
+
+// statusCode returns the status code of the API error.
+func statusCode(t *testing.T, err error) int {
+	t.Helper()
+	var errResp *godo.ErrorResponse
+	require.ErrorAs(t, err, &errResp)
+	return errResp.Response.StatusCode
+}
+
+func TestApps(t *testing.T) {
+	ctx := context.Background()
+	srv := New()
+	defer srv.Close()
+	client := newClient(t, srv)
+
+	// Enough apps to need multiple pages.
+	for i := range 25 {
+		_, _, err := client.Apps.Create(ctx, &godo.AppCreateRequest{Spec: testSpec(fmt.Sprintf("app-%d", i))})
+		require.NoError(t, err)
+	}
+
+	apps, resp, err := client.Apps.List(ctx, nil)
+	require.NoError(t, err)
+	require.Len(t, apps, defaultPerPage)
+	require.False(t, resp.Links.IsLastPage())
+	require.Equal(t, 25, resp.Meta.Total)
+
+	app, err := utils.FindAppByName(ctx, client.Apps, "app-24")
+	require.NoError(t, err)
+	require.NotNil(t, app)
+	require.Equal(t, "app-24", app.GetSpec().GetName())
+	require.NotEmpty(t, app.GetLiveURL())
+
+	_, _, err = client.Apps.Create(ctx, &godo.AppCreateRequest{Spec: testSpec("app-0")})
+	require.Equal(t, http.StatusConflict, statusCode(t, err))
+
+	_, _, err = client.Apps.Create(ctx, &godo.AppCreateRequest{Spec: testSpec("Invalid_Name")})
+	require.Equal(t, http.StatusBadRequest, statusCode(t, err))
+
+	_, _, err = client.Apps.Update(ctx, app.GetID(), &godo.AppUpdateRequest{Spec: &godo.AppSpec{Name: "app-24"}})
+	require.Equal(t, http.StatusBadRequest, statusCode(t, err))
+
+	proposal, _, err := client.Apps.Propose(ctx, &godo.AppProposeRequest{Spec: testSpec("app-0")})
+	require.NoError(t, err)
+	require.False(t, proposal.AppNameAvailable)
+	require.Equal(t, "app-0-2", proposal.AppNameSuggestion)
+	require.EqualValues(t, componentCost, proposal.AppCost)
+
+	sizes, _, err := client.Apps.ListInstanceSizes(ctx)
+	require.NoError(t, err)
+	require.Equal(t, instanceSizes, sizes)
+
+	_, err = client.Apps.Delete(ctx, app.GetID())
+	require.NoError(t, err)
+	_, _, err = client.Apps.Get(ctx, app.GetID())
+	require.Equal(t, http.StatusNotFound, statusCode(t, err))
+	_, err = client.Apps.Delete(ctx, app.GetID())
+	require.Equal(t, http.StatusNotFound, statusCode(t, err))
+	require.Len(t, srv.Apps(), 24)
+}
+
+func TestDeploymentProgress(t *testing.T) {
+	ctx := context.Background()
+	clock := &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	srv := New(WithClock(clock.Now), WithPhaseDuration(time.Minute))
+	defer srv.Close()
+	client := newClient(t, srv)
+
+	app, _, err := client.Apps.Create(ctx, &godo.AppCreateRequest{Spec: testSpec("foo")})
+	require.NoError(t, err)
+	require.Empty(t, app.GetLiveURL())
+
+	deployments, _, err := client.Apps.ListDeployments(ctx, app.GetID(), &godo.ListOptions{PerPage: 1})
+	require.NoError(t, err)
+	require.Len(t, deployments, 1)
+	deploymentID := deployments[0].GetID()
+	require.Equal(t, godo.DeploymentPhase_PendingBuild, deployments[0].GetPhase())
+
+	_, resp, err := client.Apps.GetLogs(ctx, app.GetID(), deploymentID, "", godo.AppLogTypeBuild, true, -1)
+	require.Error(t, err)
+	require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	clock.Advance(time.Minute)
+	dep, _, err := client.Apps.GetDeployment(ctx, app.GetID(), deploymentID)
+	require.NoError(t, err)
+	require.Equal(t, godo.DeploymentPhase_Building, dep.GetPhase())
+	logs, _, err := client.Apps.GetLogs(ctx, app.GetID(), deploymentID, "", godo.AppLogTypeBuild, true, -1)
+	require.NoError(t, err)
+	require.NotEmpty(t, logs.LiveURL)
+	require.Empty(t, logs.HistoricURLs)
+
+	clock.Advance(3 * time.Minute)
+	dep, _, err = client.Apps.GetDeployment(ctx, app.GetID(), deploymentID)
+	require.NoError(t, err)
+	require.Equal(t, godo.DeploymentPhase_Active, dep.GetPhase())
+	require.EqualValues(t, 2, dep.GetProgress().SuccessSteps)
+
+	logs, _, err = client.Apps.GetLogs(ctx, app.GetID(), deploymentID, "", godo.AppLogTypeBuild, true, -1)
+	require.NoError(t, err)
+	require.Empty(t, logs.LiveURL)
+	require.Len(t, logs.HistoricURLs, 1)
+	require.Equal(t, "BUILD web: done\n", get(t, logs.HistoricURLs[0]))
+
+	app, _, err = client.Apps.Get(ctx, app.GetID())
+	require.NoError(t, err)
+	require.Equal(t, deploymentID, app.GetActiveDeployment().GetID())
+	require.Equal(t, "foo is live\n", get(t, app.GetLiveURL()+"/health"))
+}
+
+func TestDeploymentSupersededAndCanceled(t *testing.T) {
+	ctx := context.Background()
+	clock := &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	srv := New(WithClock(clock.Now), WithPhaseDuration(time.Minute))
+	defer srv.Close()
+	client := newClient(t, srv)
+	deployments := utils.NewDeploymentsService(client)
+
+	app, _, err := client.Apps.Create(ctx, &godo.AppCreateRequest{Spec: testSpec("foo")})
+	require.NoError(t, err)
+	first := app.GetInProgressDeployment().GetID()
+
+	second, _, err := client.Apps.CreateDeployment(ctx, app.GetID(), &godo.DeploymentCreateRequest{ForceBuild: true})
+	require.NoError(t, err)
+
+	dep, _, err := client.Apps.GetDeployment(ctx, app.GetID(), first)
+	require.NoError(t, err)
+	require.Equal(t, godo.DeploymentPhase_Superseded, dep.GetPhase())
+
+	dep, _, err = deployments.CancelDeployment(ctx, app.GetID(), second.GetID())
+	require.NoError(t, err)
+	require.Equal(t, godo.DeploymentPhase_Canceled, dep.GetPhase())
+
+	_, _, err = deployments.CancelDeployment(ctx, app.GetID(), second.GetID())
+	require.Equal(t, http.StatusBadRequest, statusCode(t, err))
+	_, _, err = client.Apps.GetDeployment(ctx, app.GetID(), "unknown")
+	require.Equal(t, http.StatusNotFound, statusCode(t, err))
+
+	clock.Advance(time.Hour)
+	app, _, err = client.Apps.Get(ctx, app.GetID())
+	require.NoError(t, err)
+	require.Nil(t, app.GetActiveDeployment())
+	require.Nil(t, app.GetInProgressDeployment())
+	require.Empty(t, app.GetLiveURL())
+}
+
+func TestFailDeployments(t *testing.T) {
+	ctx := context.Background()
+	srv := New()
+	defer srv.Close()
+	client := newClient(t, srv)
+	srv.FailDeployments("foo")
+
+	app, _, err := client.Apps.Create(ctx, &godo.AppCreateRequest{Spec: testSpec("foo")})
+	require.NoError(t, err)
+
+	deployments := srv.Deployments(app.GetID())
+	require.Len(t, deployments, 1)
+	require.Equal(t, godo.DeploymentPhase_Error, deployments[0].GetPhase())
+	require.EqualValues(t, 1, deployments[0].GetProgress().ErrorSteps)
+
+	// The build succeeded, but the deployment didn't.
+	logs, _, err := client.Apps.GetLogs(ctx, app.GetID(), deployments[0].GetID(), "", godo.AppLogTypeDeploy, true, -1)
+	require.NoError(t, err)
+	require.Len(t, logs.HistoricURLs, 1)
+
+	_, _, err = utils.NewDeploymentsService(client).Rollback(ctx, app.GetID(), &utils.AppRollbackRequest{DeploymentID: deployments[0].GetID()})
+	require.Equal(t, http.StatusBadRequest, statusCode(t, err))
+}
+
+// get returns the body of the given URL.
+func get(t *testing.T, url string) string {
+	t.Helper()
+	resp, err := http.Get(url)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	return string(body)
+}
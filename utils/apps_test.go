@@ -46,3 +46,13 @@ func (m *mockedAppsService) List(ctx context.Context, opt *godo.ListOptions) ([]
 	args := m.Called(ctx, opt)
 	return args.Get(0).([]*godo.App), args.Get(1).(*godo.Response), args.Error(2)
 }
+
+func (m *mockedAppsService) Get(ctx context.Context, appID string) (*godo.App, *godo.Response, error) {
+	args := m.Called(ctx, appID)
+	return args.Get(0).(*godo.App), args.Get(1).(*godo.Response), args.Error(2)
+}
+
+func (m *mockedAppsService) ListInstanceSizes(ctx context.Context) ([]*godo.AppInstanceSize, *godo.Response, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]*godo.AppInstanceSize), args.Get(1).(*godo.Response), args.Error(2)
+}
@@ -0,0 +1,166 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	gha "github.com/sethvargo/go-githubactions"
+)
+
+// CIProvider is a CI system the actions can run in.
+type CIProvider string
+
+const (
+	// CIProviderGitHub is GitHub Actions.
+	CIProviderGitHub CIProvider = "github"
+	// CIProviderGitLab is GitLab CI.
+	CIProviderGitLab CIProvider = "gitlab"
+	// CIProviderGeneric is any other CI system, described via environment variables.
+	CIProviderGeneric CIProvider = "generic"
+)
+
+// genericCIEnvPrefix is the prefix of the environment variables describing
+// the context of generic CI systems.
+const genericCIEnvPrefix = "APP_ACTION_CI_"
+
+// CIContext describes the CI run the actions run in.
+type CIContext struct {
+	// Provider is the CI system.
+	Provider CIProvider
+	// Repository is the full path of the repository, for example owner/repo
+	// or group/subgroup/project for GitLab.
+	Repository string
+	// Branch is the branch the run is for. For pull and merge requests, that's
+	// their source branch.
+	Branch string
+	// Commit is the commit the run is for. For pull and merge requests, that's
+	// the head of their source branch.
+	Commit string
+	// PRNumber is the number of the pull or merge request the run is for or 0
+	// if it isn't for one.
+	PRNumber int
+	// HeadRepository is the full path of the repository the source branch of
+	// the pull or merge request lives in, if it differs from Repository. That's
+	// the case for pull requests from forks.
+	HeadRepository string
+	// ServerURL is the URL of the server hosting the repository, for example
+	// https://github.com. It's empty if unknown.
+	ServerURL string
+}
+
+// IsFork returns whether the run is for a pull or merge request from a fork.
+func (c *CIContext) IsFork() bool {
+	return c.HeadRepository != "" && c.HeadRepository != c.Repository
+}
+
+// SourceRepository returns the full path of the repository Branch lives in.
+func (c *CIContext) SourceRepository() string {
+	if c.IsFork() {
+		return c.HeadRepository
+	}
+	return c.Repository
+}
+
+// Repo returns the owner and the name of the repository. For repositories in
+// nested GitLab groups, the owner is the full path of the group.
+func (c *CIContext) Repo() (string, string) {
+	i := strings.LastIndex(c.Repository, "/")
+	if i < 0 {
+		return "", c.Repository
+	}
+	return c.Repository[:i], c.Repository[i+1:]
+}
+
+// NewCIContext detects the CI system from the environment and returns the
+// context of the current run.
+func NewCIContext(r Runtime, getenv func(string) string) (*CIContext, error) {
+	switch {
+	case getenv("GITHUB_ACTIONS") == "true":
+		ghCtx, err := r.Context()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get GitHub context: %w", err)
+		}
+		return ciContextFromGitHub(ghCtx), nil
+	case getenv("GITLAB_CI") == "true":
+		return ciContextFromGitLab(getenv)
+	default:
+		return ciContextFromEnv(getenv)
+	}
+}
+
+// ciContextFromGitHub returns the context of a GitHub Actions run.
+func ciContextFromGitHub(ghCtx *gha.GitHubContext) *CIContext {
+	c := &CIContext{
+		Provider:   CIProviderGitHub,
+		Repository: ghCtx.Repository,
+		Branch:     strings.TrimPrefix(ghCtx.Ref, "refs/heads/"),
+		Commit:     ghCtx.SHA,
+		ServerURL:  ghCtx.ServerURL,
+	}
+	if pr, ok := ghCtx.Event["pull_request"].(map[string]any); ok {
+		c.Branch = ghCtx.HeadRef
+		// The event is parsed as a JSON object and Golang represents numbers as float64.
+		if number, ok := pr["number"].(float64); ok {
+			c.PRNumber = int(number)
+		}
+		// The SHA of pull request events is the merge commit, which is never deployed.
+		head, _ := pr["head"].(map[string]any)
+		c.Commit, _ = head["sha"].(string)
+		headRepo, _ := head["repo"].(map[string]any)
+		c.HeadRepository, _ = headRepo["full_name"].(string)
+	}
+	return c
+}
+
+// ciContextFromGitLab returns the context of a GitLab CI pipeline.
+// See: https://docs.gitlab.com/ee/ci/variables/predefined_variables.html.
+func ciContextFromGitLab(getenv func(string) string) (*CIContext, error) {
+	c := &CIContext{
+		Provider:   CIProviderGitLab,
+		Repository: getenv("CI_PROJECT_PATH"),
+		Branch:     getenv("CI_COMMIT_REF_NAME"),
+		Commit:     getenv("CI_COMMIT_SHA"),
+		ServerURL:  getenv("CI_SERVER_URL"),
+	}
+	if iid := getenv("CI_MERGE_REQUEST_IID"); iid != "" {
+		number, err := strconv.Atoi(iid)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse merge request IID %q: %w", iid, err)
+		}
+		c.PRNumber = number
+		c.Branch = getenv("CI_MERGE_REQUEST_SOURCE_BRANCH_NAME")
+		c.HeadRepository = getenv("CI_MERGE_REQUEST_SOURCE_PROJECT_PATH")
+		// Merged results pipelines run on a merge commit, which is never deployed.
+		if sha := getenv("CI_MERGE_REQUEST_SOURCE_BRANCH_SHA"); sha != "" {
+			c.Commit = sha
+		}
+	}
+	return c, nil
+}
+
+// ciContextFromEnv returns the context of a generic CI system, described by
+// environment variables prefixed with APP_ACTION_CI_.
+func ciContextFromEnv(getenv func(string) string) (*CIContext, error) {
+	c := &CIContext{
+		Provider:   CIProviderGeneric,
+		Repository: getenv(genericCIEnvPrefix + "REPOSITORY"),
+		Branch:     getenv(genericCIEnvPrefix + "BRANCH"),
+		Commit:     getenv(genericCIEnvPrefix + "COMMIT"),
+		// Only needs to be set for pull requests from forks.
+		HeadRepository: getenv(genericCIEnvPrefix + "HEAD_REPOSITORY"),
+		ServerURL:      getenv(genericCIEnvPrefix + "SERVER_URL"),
+	}
+	if c.Branch == "" {
+		return nil, errors.New(genericCIEnvPrefix + "BRANCH must be set outside of GitHub Actions and GitLab CI")
+	}
+	if number := getenv(genericCIEnvPrefix + "PR_NUMBER"); number != "" {
+		var err error
+		c.PRNumber, err = strconv.Atoi(number)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse pull request number %q: %w", number, err)
+		}
+	}
+	return c, nil
+}
@@ -0,0 +1,204 @@
+package utils
+
+import (
+	"testing"
+
+	gha "github.com/sethvargo/go-githubactions"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewCIContext(t *testing.T) {
+	tests := []struct {
+		name     string
+		env      map[string]string
+		expected *CIContext
+		err      bool
+	}{{
+		name: "gitlab branch pipeline",
+		env: map[string]string{
+			"GITLAB_CI":          "true",
+			"CI_PROJECT_PATH":    "group/subgroup/project",
+			"CI_COMMIT_REF_NAME": "main",
+			"CI_COMMIT_SHA":      "abc",
+			"CI_SERVER_URL":      "https://gitlab.example.com",
+		},
+		expected: &CIContext{
+			Provider:   CIProviderGitLab,
+			Repository: "group/subgroup/project",
+			Branch:     "main",
+			Commit:     "abc",
+			ServerURL:  "https://gitlab.example.com",
+		},
+	}, {
+		name: "gitlab merge request pipeline",
+		env: map[string]string{
+			"GITLAB_CI":                            "true",
+			"CI_PROJECT_PATH":                      "group/project",
+			"CI_COMMIT_REF_NAME":                   "refs/merge-requests/3/merge",
+			"CI_COMMIT_SHA":                        "merge",
+			"CI_MERGE_REQUEST_IID":                 "3",
+			"CI_MERGE_REQUEST_SOURCE_BRANCH_NAME":  "feature",
+			"CI_MERGE_REQUEST_SOURCE_BRANCH_SHA":   "def",
+			"CI_MERGE_REQUEST_SOURCE_PROJECT_PATH": "group/project",
+		},
+		expected: &CIContext{
+			Provider:       CIProviderGitLab,
+			Repository:     "group/project",
+			Branch:         "feature",
+			Commit:         "def",
+			PRNumber:       3,
+			HeadRepository: "group/project",
+		},
+	}, {
+		name: "gitlab merge request pipeline from fork",
+		env: map[string]string{
+			"GITLAB_CI":                            "true",
+			"CI_PROJECT_PATH":                      "group/project",
+			"CI_COMMIT_SHA":                        "def",
+			"CI_MERGE_REQUEST_IID":                 "3",
+			"CI_MERGE_REQUEST_SOURCE_BRANCH_NAME":  "feature",
+			"CI_MERGE_REQUEST_SOURCE_PROJECT_PATH": "contributor/project",
+		},
+		expected: &CIContext{
+			Provider:       CIProviderGitLab,
+			Repository:     "group/project",
+			Branch:         "feature",
+			Commit:         "def",
+			PRNumber:       3,
+			HeadRepository: "contributor/project",
+		},
+	}, {
+		name: "gitlab invalid merge request IID",
+		env: map[string]string{
+			"GITLAB_CI":            "true",
+			"CI_MERGE_REQUEST_IID": "foo",
+		},
+		err: true,
+	}, {
+		name: "generic",
+		env: map[string]string{
+			"APP_ACTION_CI_REPOSITORY": "foo/bar",
+			"APP_ACTION_CI_BRANCH":     "feature",
+			"APP_ACTION_CI_COMMIT":     "abc",
+			"APP_ACTION_CI_PR_NUMBER":  "7",
+		},
+		expected: &CIContext{
+			Provider:   CIProviderGeneric,
+			Repository: "foo/bar",
+			Branch:     "feature",
+			Commit:     "abc",
+			PRNumber:   7,
+		},
+	}, {
+		name: "generic without branch",
+		env: map[string]string{
+			"APP_ACTION_CI_REPOSITORY": "foo/bar",
+		},
+		err: true,
+	}, {
+		name: "generic invalid pull request number",
+		env: map[string]string{
+			"APP_ACTION_CI_BRANCH":    "feature",
+			"APP_ACTION_CI_PR_NUMBER": "foo",
+		},
+		err: true,
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := NewCIContext(nil, func(k string) string { return test.env[k] })
+			if test.err {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, test.expected, got)
+		})
+	}
+}
+
+func TestCIContextFromGitHub(t *testing.T) {
+	require.Equal(t, &CIContext{
+		Provider:   CIProviderGitHub,
+		Repository: "foo/bar",
+		Branch:     "main",
+		Commit:     "abc",
+		ServerURL:  "https://github.com",
+	}, ciContextFromGitHub(&gha.GitHubContext{
+		Repository: "foo/bar",
+		Ref:        "refs/heads/main",
+		SHA:        "abc",
+		ServerURL:  "https://github.com",
+	}))
+	require.Equal(t, &CIContext{
+		Provider:   CIProviderGitHub,
+		Repository: "foo/bar",
+		Branch:     "feature",
+		Commit:     "def",
+		PRNumber:   3,
+	}, ciContextFromGitHub(&gha.GitHubContext{
+		Repository: "foo/bar",
+		Ref:        "refs/pull/3/merge",
+		HeadRef:    "feature",
+		SHA:        "merge",
+		Event: map[string]any{
+			"pull_request": map[string]any{
+				"number": float64(3),
+				"head":   map[string]any{"sha": "def"},
+			},
+		},
+	}))
+
+	forkCtx := ciContextFromGitHub(&gha.GitHubContext{
+		Repository: "foo/bar",
+		Ref:        "refs/pull/4/merge",
+		HeadRef:    "main",
+		SHA:        "merge",
+		Event: map[string]any{
+			"pull_request": map[string]any{
+				"number": float64(4),
+				"head": map[string]any{
+					"sha":  "ghi",
+					"repo": map[string]any{"full_name": "contributor/bar", "fork": true},
+				},
+			},
+		},
+	})
+	require.Equal(t, &CIContext{
+		Provider:       CIProviderGitHub,
+		Repository:     "foo/bar",
+		Branch:         "main",
+		Commit:         "ghi",
+		PRNumber:       4,
+		HeadRepository: "contributor/bar",
+	}, forkCtx)
+	require.True(t, forkCtx.IsFork())
+	require.Equal(t, "contributor/bar", forkCtx.SourceRepository())
+}
+
+func TestCIContextIsFork(t *testing.T) {
+	require.False(t, (&CIContext{Repository: "foo/bar"}).IsFork())
+	require.False(t, (&CIContext{Repository: "foo/bar", HeadRepository: "foo/bar"}).IsFork())
+	require.True(t, (&CIContext{Repository: "foo/bar", HeadRepository: "contributor/bar"}).IsFork())
+	require.Equal(t, "foo/bar", (&CIContext{Repository: "foo/bar"}).SourceRepository())
+}
+
+func TestCIContextRepo(t *testing.T) {
+	tests := []struct {
+		repository string
+		owner      string
+		repo       string
+	}{
+		{repository: "foo/bar", owner: "foo", repo: "bar"},
+		{repository: "group/subgroup/project", owner: "group/subgroup", repo: "project"},
+		{repository: "bar", owner: "", repo: "bar"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.repository, func(t *testing.T) {
+			owner, repo := (&CIContext{Repository: test.repository}).Repo()
+			require.Equal(t, test.owner, owner)
+			require.Equal(t, test.repo, repo)
+		})
+	}
+}
@@ -0,0 +1,222 @@
+package utils
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	gha "github.com/sethvargo/go-githubactions"
+	"sigs.k8s.io/yaml"
+)
+
+// cliEnvPrefix is the prefix of the environment variables the CLI reads inputs from.
+const cliEnvPrefix = "APP_ACTION_"
+
+// actionMetadata is the part of an action's metadata (action.yml) the CLI uses.
+type actionMetadata struct {
+	Name   string                    `json:"name"`
+	Inputs map[string]actionInputDef `json:"inputs"`
+}
+
+// actionInputDef is the definition of a single input of an action.
+type actionInputDef struct {
+	Description string `json:"description"`
+	Default     string `json:"default"`
+}
+
+// CLIRuntime runs an action as a standalone CLI. Inputs are read from flags
+// named like the inputs with dashes instead of underscores, falling back to
+// environment variables prefixed with APP_ACTION_ and the inputs' defaults.
+// Logs go to stderr. Outputs are collected and printed to stdout as a JSON
+// object by PrintOutputs.
+type CLIRuntime struct {
+	inputs map[string]string
+	getenv func(string) string
+	stdout io.Writer
+	stderr io.Writer
+	exit   func(code int)
+
+	mu      sync.Mutex
+	outputs map[string]string
+	masks   []string
+}
+
+// NewCLIRuntime returns a CLIRuntime for the action with the given metadata,
+// parsing its inputs from the given arguments and environment.
+// Inputs named token can also be read from a file via --token-file to keep
+// them out of the process list.
+func NewCLIRuntime(actionYAML []byte, args []string, getenv func(string) string, stdout, stderr io.Writer) (*CLIRuntime, error) {
+	var meta actionMetadata
+	if err := yaml.Unmarshal(actionYAML, &meta); err != nil {
+		return nil, fmt.Errorf("failed to parse action metadata: %w", err)
+	}
+
+	fs := flag.NewFlagSet(meta.Name, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	values := make(map[string]*cliInputValue, len(meta.Inputs))
+	names := make([]string, 0, len(meta.Inputs))
+	for name := range meta.Inputs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		def := meta.Inputs[name]
+		v := &cliInputValue{value: def.Default, isBool: def.Default == "true" || def.Default == "false"}
+		values[name] = v
+		fs.Var(v, strings.ReplaceAll(name, "_", "-"), def.Description)
+	}
+	var tokenFile string
+	if _, ok := meta.Inputs["token"]; ok {
+		fs.StringVar(&tokenFile, "token-file", "", "Path to a file containing the token.")
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	if fs.NArg() > 0 {
+		return nil, fmt.Errorf("unexpected arguments: %s", strings.Join(fs.Args(), " "))
+	}
+
+	inputs := make(map[string]string, len(values))
+	for name, v := range values {
+		inputs[name] = v.value
+		if env := getenv(cliEnvPrefix + strings.ToUpper(name)); env != "" && !v.set {
+			inputs[name] = env
+		}
+	}
+	if tokenFile != "" {
+		token, err := os.ReadFile(tokenFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read token file: %w", err)
+		}
+		inputs["token"] = strings.TrimSpace(string(token))
+	}
+
+	return &CLIRuntime{
+		inputs:  inputs,
+		getenv:  getenv,
+		stdout:  stdout,
+		stderr:  stderr,
+		exit:    os.Exit,
+		outputs: make(map[string]string),
+	}, nil
+}
+
+// cliInputValue is the flag.Value of a single input.
+type cliInputValue struct {
+	value  string
+	isBool bool
+	set    bool
+}
+
+// String implements flag.Value.
+func (v *cliInputValue) String() string {
+	if v == nil {
+		return ""
+	}
+	return v.value
+}
+
+// Set implements flag.Value.
+func (v *cliInputValue) Set(s string) error {
+	v.value = s
+	v.set = true
+	return nil
+}
+
+// IsBoolFlag allows boolean inputs to be passed without a value.
+func (v *cliInputValue) IsBoolFlag() bool {
+	return v.isBool
+}
+
+// GetInput implements Runtime.
+func (c *CLIRuntime) GetInput(name string) string {
+	return c.inputs[name]
+}
+
+// SetOutput implements Runtime.
+func (c *CLIRuntime) SetOutput(name, value string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.outputs[name] = value
+}
+
+// AddMask implements Runtime. Masked values are replaced in all logs.
+func (c *CLIRuntime) AddMask(value string) {
+	if value == "" {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.masks = append(c.masks, value)
+}
+
+// AddStepSummary implements Runtime. Step summaries only exist in GitHub
+// Actions, so they are discarded.
+func (c *CLIRuntime) AddStepSummary(string) {}
+
+// Group implements Runtime.
+func (c *CLIRuntime) Group(title string) {
+	c.logf("==> %s", title)
+}
+
+// EndGroup implements Runtime.
+func (c *CLIRuntime) EndGroup() {}
+
+// Infof implements Runtime.
+func (c *CLIRuntime) Infof(format string, args ...any) {
+	c.logf(format, args...)
+}
+
+// Warningf implements Runtime.
+func (c *CLIRuntime) Warningf(format string, args ...any) {
+	c.logf("warning: "+format, args...)
+}
+
+// Errorf implements Runtime.
+func (c *CLIRuntime) Errorf(format string, args ...any) {
+	c.logf("error: "+format, args...)
+}
+
+// Fatalf implements Runtime. The outputs gathered so far are printed before exiting.
+func (c *CLIRuntime) Fatalf(format string, args ...any) {
+	c.Errorf(format, args...)
+	c.PrintOutputs()
+	c.exit(1)
+}
+
+// Context implements Runtime. There is no GitHub context outside of GitHub
+// Actions. Within, for example when run by a step of a workflow, it's read
+// from the environment.
+func (c *CLIRuntime) Context() (*gha.GitHubContext, error) {
+	if c.getenv("GITHUB_ACTIONS") != "true" {
+		return nil, errors.New("the GitHub context is only available when running in GitHub Actions")
+	}
+	return gha.New(gha.WithGetenv(c.getenv)).Context()
+}
+
+// PrintOutputs prints all outputs as a JSON object to stdout.
+func (c *CLIRuntime) PrintOutputs() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := json.NewEncoder(c.stdout).Encode(c.outputs); err != nil {
+		fmt.Fprintf(c.stderr, "error: failed to print outputs: %v\n", err)
+	}
+}
+
+// logf writes a log line to stderr with all masked values replaced.
+func (c *CLIRuntime) logf(format string, args ...any) {
+	msg := fmt.Sprintf(format, args...)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, mask := range c.masks {
+		msg = strings.ReplaceAll(msg, mask, "***")
+	}
+	fmt.Fprintln(c.stderr, msg)
+}
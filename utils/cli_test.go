@@ -0,0 +1,141 @@
+package utils
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+var testActionYAML = []byte(`
+name: test
+inputs:
+  token:
+    description: The token.
+    required: true
+  app_spec_location:
+    description: Location of the app spec.
+    required: false
+    default: '.do/app.yaml'
+  dry_run:
+    description: Only plan the deployment.
+    required: false
+    default: 'false'
+`)
+
+func TestNewCLIRuntime(t *testing.T) {
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	require.NoError(t, os.WriteFile(tokenFile, []byte("file-token\n"), 0o600))
+
+	tests := []struct {
+		name     string
+		args     []string
+		env      map[string]string
+		expected map[string]string
+		err      bool
+	}{{
+		name: "defaults",
+		expected: map[string]string{
+			"token":             "",
+			"app_spec_location": ".do/app.yaml",
+			"dry_run":           "false",
+		},
+	}, {
+		name: "flags",
+		args: []string{"--token", "flag-token", "--app-spec-location=app.yaml", "--dry-run"},
+		expected: map[string]string{
+			"token":             "flag-token",
+			"app_spec_location": "app.yaml",
+			"dry_run":           "true",
+		},
+	}, {
+		name: "environment",
+		args: []string{"--app-spec-location=app.yaml"},
+		env: map[string]string{
+			"APP_ACTION_TOKEN":             "env-token",
+			"APP_ACTION_APP_SPEC_LOCATION": "ignored.yaml",
+		},
+		expected: map[string]string{
+			"token":             "env-token",
+			"app_spec_location": "app.yaml",
+			"dry_run":           "false",
+		},
+	}, {
+		name: "token file",
+		args: []string{"--token-file", tokenFile},
+		expected: map[string]string{
+			"token":             "file-token",
+			"app_spec_location": ".do/app.yaml",
+			"dry_run":           "false",
+		},
+	}, {
+		name: "unknown flag",
+		args: []string{"--foo", "bar"},
+		err:  true,
+	}, {
+		name: "positional arguments",
+		args: []string{"foo"},
+		err:  true,
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			r, err := NewCLIRuntime(testActionYAML, test.args, func(k string) string { return test.env[k] }, &stdout, &stderr)
+			if test.err {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			for name, value := range test.expected {
+				require.Equal(t, value, r.GetInput(name), name)
+			}
+		})
+	}
+}
+
+func TestCLIRuntimeLogsAndOutputs(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	r, err := NewCLIRuntime(testActionYAML, nil, func(string) string { return "" }, &stdout, &stderr)
+	require.NoError(t, err)
+	var exitCode int
+	r.exit = func(code int) { exitCode = code }
+
+	r.AddMask("secret")
+	r.Group("logs")
+	r.Infof("the token is %s", "secret")
+	r.EndGroup()
+	r.Warningf("careful")
+	r.AddStepSummary("summary")
+	r.SetOutput("foo", "bar")
+	r.SetOutput("baz", "secret")
+	r.Fatalf("failed")
+
+	require.Equal(t, 1, exitCode)
+	require.Equal(t, `==> logs
+the token is ***
+warning: careful
+error: failed
+`, stderr.String())
+	require.Equal(t, `{"baz":"secret","foo":"bar"}
+`, stdout.String())
+}
+
+func TestCLIRuntimeContext(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	env := map[string]string{}
+	r, err := NewCLIRuntime(testActionYAML, []string{"--token", "token"}, func(k string) string { return env[k] }, &stdout, &stderr)
+	require.NoError(t, err)
+
+	_, err = r.Context()
+	require.Error(t, err)
+
+	// Run by a step of a GitHub Actions workflow.
+	env["GITHUB_ACTIONS"] = "true"
+	env["GITHUB_REPOSITORY"] = "owner/repo"
+	ghCtx, err := r.Context()
+	require.NoError(t, err)
+	require.Equal(t, "owner/repo", ghCtx.Repository)
+}
@@ -0,0 +1,123 @@
+package utils
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/digitalocean/godo"
+)
+
+// ClientConfig configures the DigitalOcean API client and the HTTP client used
+// for all other requests.
+type ClientConfig struct {
+	// BaseURL is the base URL of the API. Defaults to godo's default.
+	BaseURL string
+	// ProxyURL is the URL of the proxy to send all requests through. Defaults
+	// to the proxy configured via the HTTP_PROXY, HTTPS_PROXY and NO_PROXY
+	// environment variables.
+	ProxyURL string
+	// CABundle is either a path to a file containing PEM encoded CA
+	// certificates or the certificates themselves. They're trusted in addition
+	// to the system's CAs.
+	CABundle string
+}
+
+// NewHTTPClient returns an HTTP client honoring the proxy and CA bundle of the
+// given config.
+func NewHTTPClient(cfg ClientConfig) (*http.Client, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	if cfg.ProxyURL != "" {
+		proxyURL, err := url.Parse(cfg.ProxyURL)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse proxy URL: %w", err)
+		}
+		if proxyURL.Scheme == "" || proxyURL.Host == "" {
+			return nil, fmt.Errorf("proxy URL %q must contain a scheme and a host", cfg.ProxyURL)
+		}
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+
+	if cfg.CABundle != "" {
+		pool, err := caPool(cfg.CABundle)
+		if err != nil {
+			return nil, err
+		}
+		transport.TLSClientConfig = &tls.Config{RootCAs: pool}
+	}
+	return &http.Client{Transport: transport}, nil
+}
+
+// caPool returns the system's CA pool extended by the given bundle.
+func caPool(bundle string) (*x509.CertPool, error) {
+	pemCerts := []byte(bundle)
+	if !strings.HasPrefix(strings.TrimSpace(bundle), "-----BEGIN") {
+		var err error
+		pemCerts, err = os.ReadFile(bundle)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA bundle: %w", err)
+		}
+	}
+
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		// The system's CAs are not available on all platforms.
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(pemCerts) {
+		return nil, fmt.Errorf("CA bundle doesn't contain any PEM encoded certificates")
+	}
+	return pool, nil
+}
+
+// NewClient returns a DigitalOcean API client authenticating with the given
+// token. Unlike godo.NewFromToken, the client doesn't retry failed requests by
+// itself. Retries are up to the caller, see NewRetryingAppsService.
+func NewClient(token, userAgent string, cfg ClientConfig) (*godo.Client, error) {
+	httpClient, err := NewHTTPClient(cfg)
+	if err != nil {
+		return nil, err
+	}
+	client := godo.NewClient(&http.Client{
+		Transport: &tokenTransport{
+			token: strings.Trim(strings.TrimSpace(token), "'"),
+			base:  httpClient.Transport,
+		},
+	})
+	client.UserAgent = userAgent
+
+	if cfg.BaseURL != "" {
+		baseURL, err := url.Parse(cfg.BaseURL)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse API base URL: %w", err)
+		}
+		if baseURL.Scheme != "http" && baseURL.Scheme != "https" {
+			return nil, fmt.Errorf("API base URL %q must be an HTTP(S) URL", cfg.BaseURL)
+		}
+		// Paths are resolved relative to the base URL, which only works if it ends with a slash.
+		if !strings.HasSuffix(baseURL.Path, "/") {
+			baseURL.Path += "/"
+		}
+		client.BaseURL = baseURL
+	}
+	return client, nil
+}
+
+// tokenTransport authenticates all requests with a bearer token.
+type tokenTransport struct {
+	token string
+	base  http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *tokenTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// RoundTrippers must not modify the original request.
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", "Bearer "+t.token)
+	return t.base.RoundTrip(req)
+}
@@ -0,0 +1,153 @@
+package utils
+
+import (
+	"context"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewClient(t *testing.T) {
+	var requests int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		require.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+		require.Equal(t, "test-agent", r.Header.Get("User-Agent"))
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	client, err := NewClient(" 'token' ", "test-agent", ClientConfig{BaseURL: srv.URL})
+	require.NoError(t, err)
+
+	_, _, err = client.Apps.Get(context.Background(), "app-id")
+	require.Error(t, err)
+	// The client itself must not retry.
+	require.Equal(t, 1, requests)
+}
+
+func TestNewClientBaseURL(t *testing.T) {
+	tests := []struct {
+		name     string
+		baseURL  string
+		expected string
+		err      bool
+	}{{
+		name:     "default",
+		expected: "https://api.digitalocean.com/",
+	}, {
+		name:     "custom",
+		baseURL:  "http://localhost:8080",
+		expected: "http://localhost:8080/",
+	}, {
+		name:     "custom with path",
+		baseURL:  "https://proxy.example.com/digitalocean",
+		expected: "https://proxy.example.com/digitalocean/",
+	}, {
+		name:    "not HTTP",
+		baseURL: "ftp://example.com",
+		err:     true,
+	}, {
+		name:    "invalid",
+		baseURL: "http://[::1",
+		err:     true,
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			client, err := NewClient("token", "test-agent", ClientConfig{BaseURL: test.baseURL})
+			if test.err {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, test.expected, client.BaseURL.String())
+		})
+	}
+}
+
+func TestNewHTTPClientProxy(t *testing.T) {
+	var proxied []string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Proxied requests carry the absolute URL of the target.
+		proxied = append(proxied, r.URL.String())
+		require.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"app": {"id": "app-id"}}`))
+	}))
+	defer proxy.Close()
+
+	client, err := NewClient("token", "test-agent", ClientConfig{
+		BaseURL:  "http://api.example.com",
+		ProxyURL: proxy.URL,
+	})
+	require.NoError(t, err)
+
+	app, _, err := client.Apps.Get(context.Background(), "app-id")
+	require.NoError(t, err)
+	require.Equal(t, "app-id", app.GetID())
+	require.Equal(t, []string{"http://api.example.com/v2/apps/app-id"}, proxied)
+
+	_, err = NewHTTPClient(ClientConfig{ProxyURL: "proxy.example.com"})
+	require.Error(t, err)
+}
+
+func TestNewHTTPClientCABundle(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	caPEM := string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw}))
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	require.NoError(t, os.WriteFile(caFile, []byte(caPEM), 0o600))
+
+	tests := []struct {
+		name     string
+		caBundle string
+		err      bool
+		getErr   bool
+	}{{
+		name:   "system CAs only",
+		getErr: true,
+	}, {
+		name:     "bundle file",
+		caBundle: caFile,
+	}, {
+		name:     "inline bundle",
+		caBundle: caPEM,
+	}, {
+		name:     "missing file",
+		caBundle: filepath.Join(t.TempDir(), "missing.pem"),
+		err:      true,
+	}, {
+		name:     "no certificates",
+		caBundle: "-----BEGIN CERTIFICATE-----\nfoo\n-----END CERTIFICATE-----\n",
+		err:      true,
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			client, err := NewHTTPClient(ClientConfig{CABundle: test.caBundle})
+			if test.err {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+
+			resp, err := client.Get(srv.URL)
+			if test.getErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			resp.Body.Close()
+			require.Equal(t, http.StatusNoContent, resp.StatusCode)
+		})
+	}
+}
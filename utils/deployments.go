@@ -0,0 +1,70 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/digitalocean/godo"
+)
+
+// DeploymentsService covers the deployment related App Platform endpoints that
+// godo.AppsService doesn't expose.
+type DeploymentsService interface {
+	CancelDeployment(ctx context.Context, appID, deploymentID string) (*godo.Deployment, *godo.Response, error)
+	Rollback(ctx context.Context, appID string, rollback *AppRollbackRequest) (*godo.Deployment, *godo.Response, error)
+}
+
+// AppRollbackRequest is the request to roll an app back to a previous deployment.
+type AppRollbackRequest struct {
+	// DeploymentID is the ID of the deployment to roll back to.
+	DeploymentID string `json:"deployment_id"`
+	// SkipPin skips pinning the app to the rollback deployment. A pinned app
+	// doesn't accept new deployments until the rollback is committed or reverted.
+	SkipPin bool `json:"skip_pin"`
+}
+
+// NewDeploymentsService returns a DeploymentsService backed by the given client.
+func NewDeploymentsService(client *godo.Client) DeploymentsService {
+	return &deploymentsService{client: client}
+}
+
+// deploymentsService implements DeploymentsService on top of godo's raw request handling.
+type deploymentsService struct {
+	client *godo.Client
+}
+
+// deploymentRoot is the envelope the API wraps single deployments in.
+type deploymentRoot struct {
+	Deployment *godo.Deployment `json:"deployment"`
+}
+
+// CancelDeployment cancels the given deployment.
+func (s *deploymentsService) CancelDeployment(ctx context.Context, appID, deploymentID string) (*godo.Deployment, *godo.Response, error) {
+	path := fmt.Sprintf("v2/apps/%s/deployments/%s/cancel", appID, deploymentID)
+	req, err := s.client.NewRequest(ctx, http.MethodPost, path, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	root := new(deploymentRoot)
+	resp, err := s.client.Do(ctx, req, root)
+	if err != nil {
+		return nil, resp, err
+	}
+	return root.Deployment, resp, nil
+}
+
+// Rollback rolls the app back to the deployment given in the request.
+func (s *deploymentsService) Rollback(ctx context.Context, appID string, rollback *AppRollbackRequest) (*godo.Deployment, *godo.Response, error) {
+	path := fmt.Sprintf("v2/apps/%s/rollback", appID)
+	req, err := s.client.NewRequest(ctx, http.MethodPost, path, rollback)
+	if err != nil {
+		return nil, nil, err
+	}
+	root := new(deploymentRoot)
+	resp, err := s.client.Do(ctx, req, root)
+	if err != nil {
+		return nil, resp, err
+	}
+	return root.Deployment, resp, nil
+}
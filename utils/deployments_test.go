@@ -0,0 +1,65 @@
+package utils
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/digitalocean/godo"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCancelDeployment(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/v2/apps/app-id/deployments/deployment-id/cancel" {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"id":"not_found","message":"not found"}`))
+			return
+		}
+		w.Write([]byte(`{"deployment":{"id":"deployment-id","phase":"CANCELED"}}`))
+	}))
+	defer srv.Close()
+
+	client, err := godo.New(srv.Client(), godo.SetBaseURL(srv.URL))
+	require.NoError(t, err)
+	ds := NewDeploymentsService(client)
+
+	dep, _, err := ds.CancelDeployment(context.Background(), "app-id", "deployment-id")
+	require.NoError(t, err)
+	require.Equal(t, &godo.Deployment{ID: "deployment-id", Phase: godo.DeploymentPhase_Canceled}, dep)
+
+	_, resp, err := ds.CancelDeployment(context.Background(), "app-id", "another-id")
+	require.Error(t, err)
+	require.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+func TestRollback(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req AppRollbackRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || r.Method != http.MethodPost || r.URL.Path != "/v2/apps/app-id/rollback" {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"id":"bad_request","message":"bad request"}`))
+			return
+		}
+		if req.DeploymentID != "previous-id" || !req.SkipPin {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"id":"bad_request","message":"unexpected rollback request"}`))
+			return
+		}
+		w.Write([]byte(`{"deployment":{"id":"rollback-id","phase":"PENDING_DEPLOY"}}`))
+	}))
+	defer srv.Close()
+
+	client, err := godo.New(srv.Client(), godo.SetBaseURL(srv.URL))
+	require.NoError(t, err)
+	ds := NewDeploymentsService(client)
+
+	dep, _, err := ds.Rollback(context.Background(), "app-id", &AppRollbackRequest{DeploymentID: "previous-id", SkipPin: true})
+	require.NoError(t, err)
+	require.Equal(t, &godo.Deployment{ID: "rollback-id", Phase: godo.DeploymentPhase_PendingDeploy}, dep)
+
+	_, _, err = ds.Rollback(context.Background(), "app-id", &AppRollbackRequest{DeploymentID: "previous-id"})
+	require.Error(t, err)
+}
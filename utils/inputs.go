@@ -3,12 +3,11 @@ package utils
 import (
 	"fmt"
 	"strconv"
-
-	gha "github.com/sethvargo/go-githubactions"
+	"time"
 )
 
 // InputAsString parses the input as a string and sets the target.
-func InputAsString(a *gha.Action, input string, required bool, target *string) error {
+func InputAsString(a Runtime, input string, required bool, target *string) error {
 	str := a.GetInput(input)
 	if str == "" && required {
 		return fmt.Errorf("input %q is required", input)
@@ -18,7 +17,7 @@ func InputAsString(a *gha.Action, input string, required bool, target *string) e
 }
 
 // InputAsBool parses the input as a boolean and sets the target.
-func InputAsBool(a *gha.Action, input string, required bool, target *bool) error {
+func InputAsBool(a Runtime, input string, required bool, target *bool) error {
 	str := a.GetInput(input)
 	if str == "" {
 		if required {
@@ -36,3 +35,45 @@ func InputAsBool(a *gha.Action, input string, required bool, target *bool) error
 	*target = val
 	return nil
 }
+
+// InputAsInt parses the input as an integer and sets the target.
+func InputAsInt(a Runtime, input string, required bool, target *int) error {
+	str := a.GetInput(input)
+	if str == "" {
+		if required {
+			return fmt.Errorf("input %q is required", input)
+		}
+
+		// If the input is not required, we default to 0.
+		*target = 0
+		return nil
+	}
+	val, err := strconv.Atoi(str)
+	if err != nil {
+		return fmt.Errorf("failed to parse %q as an integer: %v", input, err)
+	}
+	*target = val
+	return nil
+}
+
+// InputAsDuration parses the input as a duration (for example "15m") and sets the target.
+// An empty, optional input results in a zero duration.
+func InputAsDuration(a Runtime, input string, required bool, target *time.Duration) error {
+	str := a.GetInput(input)
+	if str == "" {
+		if required {
+			return fmt.Errorf("input %q is required", input)
+		}
+		*target = 0
+		return nil
+	}
+	val, err := time.ParseDuration(str)
+	if err != nil {
+		return fmt.Errorf("failed to parse %q as a duration: %v", input, err)
+	}
+	if val < 0 {
+		return fmt.Errorf("input %q must not be negative", input)
+	}
+	*target = val
+	return nil
+}
@@ -1,55 +1,91 @@
 package utils
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"regexp"
+	"strconv"
 	"strings"
 
 	"github.com/digitalocean/godo"
 	gha "github.com/sethvargo/go-githubactions"
 )
 
+// PreviewConfig configures how app specs are turned into pull request previews.
+type PreviewConfig struct {
+	// PreserveDomains keeps the spec's domains instead of unsetting them. Their
+	// tokens are substituted, see SubstituteDomainTokens.
+	PreserveDomains bool
+	// NameTemplate is the template of the preview app's name, see GenerateAppName.
+	NameTemplate string
+	// AllowForks allows previews of pull requests from forks. Their sources
+	// point to the fork, so the preview runs code that wasn't reviewed by the
+	// repository's maintainers.
+	AllowForks bool
+	// Resources limits the resources of the preview app, if set.
+	Resources *PreviewResourcePolicy
+	// InstanceSizes are the instance sizes available on App Platform. They're
+	// required if Resources sets an instance size.
+	InstanceSizes []*godo.AppInstanceSize
+}
+
 // SanitizeSpecForPullRequestPreview modifies the given AppSpec to be suitable for a pull request preview.
 // This includes:
 // - Setting a unique app name.
-// - Optionally unsetting any domains (unless preserveDomains is true).
+// - Optionally unsetting any domains (unless cfg.PreserveDomains is true).
 // - Unsetting any alerts.
-// - Setting the reference of all relevant components to point to the PRs ref.
-func SanitizeSpecForPullRequestPreview(spec *godo.AppSpec, ghCtx *gha.GitHubContext, preserveDomains bool) error {
-	repoOwner, repo := ghCtx.Repo()
+// - Applying the resource policy, if any.
+// - Setting the reference of all relevant components to point to the PRs ref,
+// including the fork's repository for PRs from forks. Relevant are all
+// GitHub, GitLab, Bitbucket and Git sources of the current repository,
+// matched by their normalized clone URL.
+func SanitizeSpecForPullRequestPreview(spec *godo.AppSpec, ciCtx *CIContext, cfg PreviewConfig) error {
+	if ciCtx.IsFork() && !cfg.AllowForks {
+		return fmt.Errorf("previews of pull requests from forks are not allowed, but the branch lives in %s", ciCtx.HeadRepository)
+	}
 
 	// Override app name to something that identifies this PR.
-	spec.Name = GenerateAppName(repoOwner, repo, ghCtx.HeadRef)
+	name, err := GenerateAppName(cfg.NameTemplate, ciCtx)
+	if err != nil {
+		return fmt.Errorf("failed to generate app name: %w", err)
+	}
+	spec.Name = name
 
 	// Unset any domains as those might collide with production apps.
-	// UNLESS preserveDomains is explicitly true.
-	if !preserveDomains {
+	// UNLESS cfg.PreserveDomains is explicitly true.
+	if !cfg.PreserveDomains {
 		spec.Domains = nil
 	}
 
 	// Unset any alerts as those will be delivered wrongly anyway.
 	spec.Alerts = nil
 
+	// Keep the costs of previews down.
+	if cfg.Resources != nil {
+		if err := cfg.Resources.Apply(spec, cfg.InstanceSizes); err != nil {
+			return fmt.Errorf("failed to apply preview resource policy: %w", err)
+		}
+	}
+
 	// Override the reference of all relevant components to point to the PRs ref.
 	if err := godo.ForEachAppSpecComponent(spec, func(c godo.AppBuildableComponentSpec) error {
-		// TODO: Should this also deal with raw Git sources?
-		ref := c.GetGitHub()
-		if ref == nil || ref.Repo != fmt.Sprintf("%s/%s", repoOwner, repo) {
-			// Skip Github refs pointing to other repos.
+		// Sources pointing to other repos are skipped.
+		src := VCSSourceOf(c)
+		if src == nil || !ciCtx.isRepository(src) {
 			return nil
 		}
 		// We manually kick new deployments so we can watch their status better.
-		ref.DeployOnPush = false
-		ref.Branch = ghCtx.HeadRef
+		src.set(ciCtx.SourceRepository(), ciCtx.Branch)
 		return nil
 	}); err != nil {
 		return fmt.Errorf("failed to sanitize buildable components: %w", err)
 	}
 
 	// Substitute domain tokens if domains are preserved
-	if preserveDomains && spec.Domains != nil {
-		if err := SubstituteDomainTokens(spec, ghCtx); err != nil {
+	if cfg.PreserveDomains && spec.Domains != nil {
+		if err := SubstituteDomainTokens(spec, ciCtx); err != nil {
 			return fmt.Errorf("failed to substitute domain tokens: %w", err)
 		}
 	}
@@ -57,11 +93,89 @@ func SanitizeSpecForPullRequestPreview(spec *godo.AppSpec, ghCtx *gha.GitHubCont
 	return nil
 }
 
-// GenerateAppName generates an app name based on the branch name.
-// App names must be at most 32 characters.
-func GenerateAppName(repoOwner, repo, branchName string) string {
-	baseName := branchName
-	baseName = strings.ToLower(baseName)
+const (
+	// maxAppNameLength is the maximum length of app names.
+	maxAppNameLength = 32
+	// appNameHashLength is the length of the hash truncated app names end with.
+	appNameHashLength = 6
+)
+
+// appNameTokenRegexp matches the tokens of app name templates.
+var appNameTokenRegexp = regexp.MustCompile(`\{[A-Z_]+\}`)
+
+// GenerateAppName generates the name of the preview app of the pull request
+// in the given context, based on the given template. The template may contain
+// the tokens {REPO}, {OWNER}, {BRANCH}, {PR_NUMBER} and {SHORT_SHA}. If it's
+// empty, the name is made up of the pull request (or the branch outside of
+// pull requests), the repository and its owner, in that order.
+// The name is sanitized to only contain lowercase alphanumeric characters and
+// hyphens. App names must be at most 32 characters. Longer names are truncated
+// and end with a hash of the untruncated name to keep them unique.
+func GenerateAppName(template string, ciCtx *CIContext) (string, error) {
+	if template == "" {
+		template = "pr-{PR_NUMBER}-{REPO}-{OWNER}"
+		if ciCtx.PRNumber == 0 {
+			template = "{BRANCH}-{REPO}-{OWNER}"
+		}
+	}
+
+	repoOwner, repo := ciCtx.Repo()
+	tokens := map[string]string{
+		"{REPO}":      repo,
+		"{OWNER}":     repoOwner,
+		"{BRANCH}":    ciCtx.Branch,
+		"{PR_NUMBER}": "",
+		"{SHORT_SHA}": ciCtx.Commit[:min(len(ciCtx.Commit), 7)],
+	}
+	if ciCtx.PRNumber != 0 {
+		tokens["{PR_NUMBER}"] = strconv.Itoa(ciCtx.PRNumber)
+	}
+	var unknown []string
+	fullName := appNameTokenRegexp.ReplaceAllStringFunc(template, func(token string) string {
+		value, ok := tokens[token]
+		if !ok {
+			unknown = append(unknown, token)
+		}
+		return value
+	})
+	if len(unknown) > 0 {
+		return "", fmt.Errorf("unknown tokens in app name template %q: %s", template, strings.Join(unknown, ", "))
+	}
+
+	name := sanitizeAppName(fullName)
+	// Empty tokens leave consecutive hyphens behind.
+	name = regexp.MustCompile(`-{2,}`).ReplaceAllString(name, "-")
+	if name == "" || name[0] < 'a' || name[0] > 'z' {
+		return "", fmt.Errorf("app name %q generated from template %q must start with a letter", name, template)
+	}
+	if len(name) <= maxAppNameLength {
+		return name, nil
+	}
+
+	hash := sha256.Sum256([]byte(fullName))
+	name = strings.TrimRight(name[:maxAppNameLength-appNameHashLength-1], "-")
+	return name + "-" + hex.EncodeToString(hash[:])[:appNameHashLength], nil
+}
+
+// LegacyAppName returns the name preview apps got before GenerateAppName took
+// the repository and the pull request into account. As it's only derived from
+// the branch, it might be the name of a preview app of another repository.
+func LegacyAppName(branchName string) string {
+	baseName := sanitizeAppName(branchName)
+
+	// Truncate to 32 characters max
+	if len(baseName) > maxAppNameLength {
+		baseName = baseName[:maxAppNameLength]
+		// Trim trailing hyphen if truncation created one
+		baseName = strings.TrimRight(baseName, "-")
+	}
+
+	return baseName
+}
+
+// sanitizeAppName turns the given name into a valid app name, apart from its length.
+func sanitizeAppName(name string) string {
+	baseName := strings.ToLower(name)
 	baseName = strings.NewReplacer(
 		"/", "-",
 		"_", "-",
@@ -70,35 +184,51 @@ func GenerateAppName(repoOwner, repo, branchName string) string {
 	// Remove any non-alphanumeric characters except hyphens
 	baseName = regexp.MustCompile(`[^a-z0-9-]`).ReplaceAllString(baseName, "")
 	// Trim leading/trailing hyphens
-	baseName = strings.Trim(baseName, "-")
+	return strings.Trim(baseName, "-")
+}
 
-	// Truncate to 32 characters max
-	if len(baseName) > 32 {
-		baseName = baseName[:32]
-		// Trim trailing hyphen if truncation created one
-		baseName = strings.TrimRight(baseName, "-")
+// IsPreviewFor returns whether the spec looks like the spec of a preview app
+// of the branch of the repository (or fork) in the given context. That is, it
+// has a component built from the branch, none of the components built from
+// the repository deploy on push and it has neither domains nor alerts.
+func IsPreviewFor(spec *godo.AppSpec, ciCtx *CIContext) bool {
+	if len(spec.Domains) > 0 || len(spec.Alerts) > 0 {
+		return false
 	}
 
-	return baseName
+	var found, deployOnPush bool
+	_ = godo.ForEachAppSpecComponent(spec, func(c godo.AppBuildableComponentSpec) error {
+		src := VCSSourceOf(c)
+		if src == nil {
+			return nil
+		}
+		if src.DeployOnPush && (ciCtx.isRepository(src) || ciCtx.IsSourceRepository(src)) {
+			// Previews never deploy on push, long-lived apps of the branch might.
+			deployOnPush = true
+		}
+		if ciCtx.IsSourceRepository(src) && src.Branch == ciCtx.Branch {
+			found = true
+		}
+		return nil
+	})
+	return found && !deployOnPush
 }
 
 // SubstituteDomainTokens replaces tokens in domain specifications with PR-specific values.
 // Supports tokens like {BRANCH}, {PR_NUMBER}, {REPO}, {OWNER}
-func SubstituteDomainTokens(spec *godo.AppSpec, ghCtx *gha.GitHubContext) error {
+func SubstituteDomainTokens(spec *godo.AppSpec, ciCtx *CIContext) error {
 	if spec.Domains == nil {
 		return nil
 	}
 
-	repoOwner, repo := ghCtx.Repo()
+	repoOwner, repo := ciCtx.Repo()
 	prNumber := ""
-	if prFields, ok := ghCtx.Event["pull_request"].(map[string]any); ok {
-		if num, ok := prFields["number"].(float64); ok {
-			prNumber = fmt.Sprintf("%d", int(num))
-		}
+	if ciCtx.PRNumber != 0 {
+		prNumber = fmt.Sprintf("%d", ciCtx.PRNumber)
 	}
 
 	// Sanitize branch name for DNS compliance
-	branchName := ghCtx.HeadRef
+	branchName := ciCtx.Branch
 	safeBranchName := strings.ToLower(branchName)
 	safeBranchName = strings.NewReplacer(
 		"/", "-",
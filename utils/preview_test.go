@@ -4,7 +4,6 @@ import (
 	"testing"
 
 	"github.com/digitalocean/godo"
-	gha "github.com/sethvargo/go-githubactions"
 	"github.com/stretchr/testify/require"
 )
 
@@ -54,21 +53,18 @@ func TestSanitizeSpecForPullRequestPreview(t *testing.T) {
 		}},
 	}
 
-	ghCtx := &gha.GitHubContext{
+	ciCtx := &CIContext{
+		Provider:   CIProviderGitHub,
 		Repository: "foo/bar",
-		HeadRef:    "feature-branch",
-		Event: map[string]any{
-			"pull_request": map[string]any{
-				"number": float64(3),
-			},
-		},
+		Branch:     "feature-branch",
+		PRNumber:   3,
 	}
 
-	err := SanitizeSpecForPullRequestPreview(spec, ghCtx, false)
+	err := SanitizeSpecForPullRequestPreview(spec, ciCtx, PreviewConfig{})
 	require.NoError(t, err)
 
 	expected := &godo.AppSpec{
-		Name: "feature-branch", // Name got generated.
+		Name: "pr-3-bar-foo", // Name got generated.
 		// Domains and alerts got removed.
 		Services: []*godo.AppServiceSpec{{
 			Name: "web",
@@ -114,49 +110,292 @@ func TestSanitizeSpecForPullRequestPreview(t *testing.T) {
 	require.Equal(t, expected, spec)
 }
 
+func TestSanitizeSpecForMergeRequestPreview(t *testing.T) {
+	spec := &godo.AppSpec{
+		Name: "foo",
+		Services: []*godo.AppServiceSpec{{
+			Name: "web",
+			GitLab: &godo.GitLabSourceSpec{
+				Repo:         "group/subgroup/project",
+				Branch:       "main",
+				DeployOnPush: true,
+			},
+		}, {
+			Name: "web2",
+			GitLab: &godo.GitLabSourceSpec{
+				Repo:         "group/other",
+				Branch:       "main",
+				DeployOnPush: true,
+			},
+		}},
+	}
+
+	ciCtx := &CIContext{
+		Provider:   CIProviderGitLab,
+		Repository: "group/subgroup/project",
+		Branch:     "feature-branch",
+		PRNumber:   3,
+	}
+
+	err := SanitizeSpecForPullRequestPreview(spec, ciCtx, PreviewConfig{})
+	require.NoError(t, err)
+
+	expected := &godo.AppSpec{
+		Name: "pr-3-project-group-subgroup",
+		Services: []*godo.AppServiceSpec{{
+			Name: "web",
+			GitLab: &godo.GitLabSourceSpec{
+				Repo:         "group/subgroup/project",
+				Branch:       "feature-branch", // Branch got updated.
+				DeployOnPush: false,            // DeployOnPush got set to false.
+			},
+		}, {
+			Name: "web2",
+			GitLab: &godo.GitLabSourceSpec{
+				Repo:         "group/other", // No change.
+				Branch:       "main",
+				DeployOnPush: true,
+			},
+		}},
+	}
+
+	require.Equal(t, expected, spec)
+}
+
+func TestSanitizeSpecForForkPullRequestPreview(t *testing.T) {
+	newSpec := func() *godo.AppSpec {
+		return &godo.AppSpec{
+			Name: "foo",
+			Services: []*godo.AppServiceSpec{{
+				Name:   "web",
+				GitHub: &godo.GitHubSourceSpec{Repo: "foo/bar", Branch: "main", DeployOnPush: true},
+			}, {
+				Name:   "web2",
+				GitHub: &godo.GitHubSourceSpec{Repo: "another/repo", Branch: "main", DeployOnPush: true},
+			}},
+		}
+	}
+	ciCtx := &CIContext{
+		Provider:       CIProviderGitHub,
+		Repository:     "foo/bar",
+		Branch:         "main",
+		PRNumber:       4,
+		HeadRepository: "contributor/bar",
+	}
+
+	err := SanitizeSpecForPullRequestPreview(newSpec(), ciCtx, PreviewConfig{})
+	require.ErrorContains(t, err, "forks are not allowed")
+
+	spec := newSpec()
+	err = SanitizeSpecForPullRequestPreview(spec, ciCtx, PreviewConfig{AllowForks: true})
+	require.NoError(t, err)
+	require.Equal(t, &godo.AppSpec{
+		Name: "pr-4-bar-foo",
+		Services: []*godo.AppServiceSpec{{
+			Name:   "web",
+			GitHub: &godo.GitHubSourceSpec{Repo: "contributor/bar", Branch: "main"}, // Repo points to the fork.
+		}, {
+			Name:   "web2",
+			GitHub: &godo.GitHubSourceSpec{Repo: "another/repo", Branch: "main", DeployOnPush: true}, // No change.
+		}},
+	}, spec)
+	require.True(t, IsPreviewFor(spec, ciCtx))
+}
+
 func TestGenerateAppName(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+		ciCtx    *CIContext
+		expected string
+		err      bool
+	}{{
+		name:     "pull request",
+		ciCtx:    &CIContext{Repository: "foo/bar", Branch: "feature-test", PRNumber: 3},
+		expected: "pr-3-bar-foo",
+	}, {
+		name:     "branch",
+		ciCtx:    &CIContext{Repository: "foo/bar", Branch: "feature/test_v2.1"},
+		expected: "feature-test-v2-1-bar-foo",
+	}, {
+		name:     "nested group",
+		ciCtx:    &CIContext{Repository: "group/subgroup/bar", PRNumber: 3},
+		expected: "pr-3-bar-group-subgroup",
+	}, {
+		name:     "long repository",
+		ciCtx:    &CIContext{Repository: "digitalocean/sample-golang-with-a-long-name", PRNumber: 42},
+		expected: "pr-42-sample-golang-with-ad3ab8",
+	}, {
+		name:     "long repository of another owner",
+		ciCtx:    &CIContext{Repository: "digitalocean-labs/sample-golang-with-a-long-name", PRNumber: 42},
+		expected: "pr-42-sample-golang-with-26aea1",
+	}, {
+		name:     "long branch",
+		ciCtx:    &CIContext{Repository: "foo/bar", Branch: "this-is-an-extremely-long-branch-name-that-exceeds-the-limit"},
+		expected: "this-is-an-extremely-long-e567e1",
+	}, {
+		name:     "template",
+		template: "pr-{PR_NUMBER}-{REPO}",
+		ciCtx:    &CIContext{Repository: "foo/Bar_Baz", PRNumber: 3},
+		expected: "pr-3-bar-baz",
+	}, {
+		name:     "template with all tokens",
+		template: "{REPO}-{OWNER}-{BRANCH}-{PR_NUMBER}-{SHORT_SHA}",
+		ciCtx:    &CIContext{Repository: "foo/bar", Branch: "fix", PRNumber: 3, Commit: "0123456789abcdef"},
+		expected: "bar-foo-fix-3-0123456",
+	}, {
+		name:     "template with empty tokens",
+		template: "{REPO}-{PR_NUMBER}-{SHORT_SHA}-preview",
+		ciCtx:    &CIContext{Repository: "foo/bar"},
+		expected: "bar-preview",
+	}, {
+		name:     "long template",
+		template: "preview-of-{REPO}-{BRANCH}",
+		ciCtx:    &CIContext{Repository: "foo/bar", Branch: "feature/a-very-long-branch-name"},
+		expected: "preview-of-bar-feature-a-f28740",
+	}, {
+		name:     "unknown token",
+		template: "{REPO}-{PR}",
+		ciCtx:    &CIContext{Repository: "foo/bar", PRNumber: 3},
+		err:      true,
+	}, {
+		name:     "not starting with a letter",
+		template: "{PR_NUMBER}-{REPO}",
+		ciCtx:    &CIContext{Repository: "foo/bar", PRNumber: 3},
+		err:      true,
+	}, {
+		name:     "empty",
+		template: "{PR_NUMBER}",
+		ciCtx:    &CIContext{Repository: "foo/bar"},
+		err:      true,
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := GenerateAppName(test.template, test.ciCtx)
+			if test.err {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, test.expected, got)
+			require.LessOrEqual(t, len(got), maxAppNameLength)
+		})
+	}
+}
+
+func TestLegacyAppName(t *testing.T) {
 	tests := []struct {
 		name       string
-		repoOwner  string
-		repo       string
 		branchName string
 		expected   string
 	}{{
 		name:       "success",
-		repoOwner:  "foo",
-		repo:       "bar",
 		branchName: "feature-test-do-deploy2",
 		expected:   "feature-test-do-deploy2",
 	}, {
 		name:       "branch with slashes",
-		repoOwner:  "foo",
-		repo:       "bar",
 		branchName: "feature/test",
 		expected:   "feature-test",
 	}, {
 		name:       "branch with underscores and dots",
-		repoOwner:  "foo",
-		repo:       "bar",
 		branchName: "feature_test.v2",
 		expected:   "feature-test-v2",
 	}, {
 		name:       "long branch name",
-		repoOwner:  "foo",
-		repo:       "bar",
 		branchName: "this-is-an-extremely-long-branch-name-that-exceeds-the-limit",
 		expected:   "this-is-an-extremely-long-branch",
 	}, {
 		name:       "long branch with truncation at hyphen",
-		repoOwner:  "foo",
-		repo:       "bar",
 		branchName: "feature-with-a-very-long-name-",
 		expected:   "feature-with-a-very-long-name",
 	}}
 
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
-			got := GenerateAppName(test.repoOwner, test.repo, test.branchName)
+			got := LegacyAppName(test.branchName)
 			require.Equal(t, test.expected, got)
 		})
 	}
 }
+
+func TestIsPreviewFor(t *testing.T) {
+	ciCtx := &CIContext{Repository: "foo/bar", Branch: "feature"}
+	tests := []struct {
+		name     string
+		spec     *godo.AppSpec
+		expected bool
+	}{{
+		name: "github source",
+		spec: &godo.AppSpec{Services: []*godo.AppServiceSpec{{
+			Name:   "web",
+			GitHub: &godo.GitHubSourceSpec{Repo: "foo/bar", Branch: "feature"},
+		}}},
+		expected: true,
+	}, {
+		name: "gitlab source",
+		spec: &godo.AppSpec{Workers: []*godo.AppWorkerSpec{{
+			Name:   "worker",
+			GitLab: &godo.GitLabSourceSpec{Repo: "foo/bar", Branch: "feature"},
+		}}},
+		expected: true,
+	}, {
+		name: "deploy on push",
+		spec: &godo.AppSpec{Services: []*godo.AppServiceSpec{{
+			Name:   "web",
+			GitHub: &godo.GitHubSourceSpec{Repo: "foo/bar", Branch: "feature", DeployOnPush: true},
+		}}},
+	}, {
+		name: "other repository deploys on push",
+		spec: &godo.AppSpec{Services: []*godo.AppServiceSpec{{
+			Name:   "web",
+			GitHub: &godo.GitHubSourceSpec{Repo: "foo/bar", Branch: "feature"},
+		}, {
+			Name:   "web2",
+			GitHub: &godo.GitHubSourceSpec{Repo: "foo/baz", Branch: "main", DeployOnPush: true},
+		}}},
+		expected: true,
+	}, {
+		name: "domains",
+		spec: &godo.AppSpec{
+			Services: []*godo.AppServiceSpec{{
+				Name:   "web",
+				GitHub: &godo.GitHubSourceSpec{Repo: "foo/bar", Branch: "feature"},
+			}},
+			Domains: []*godo.AppDomainSpec{{Domain: "feature.example.com"}},
+		},
+	}, {
+		name: "alerts",
+		spec: &godo.AppSpec{
+			Services: []*godo.AppServiceSpec{{
+				Name:   "web",
+				GitHub: &godo.GitHubSourceSpec{Repo: "foo/bar", Branch: "feature"},
+			}},
+			Alerts: []*godo.AppAlertSpec{{Rule: godo.AppAlertSpecRule_DeploymentFailed}},
+		},
+	}, {
+		name: "other branch",
+		spec: &godo.AppSpec{Services: []*godo.AppServiceSpec{{
+			Name:   "web",
+			GitHub: &godo.GitHubSourceSpec{Repo: "foo/bar", Branch: "main"},
+		}}},
+	}, {
+		name: "other repository",
+		spec: &godo.AppSpec{Services: []*godo.AppServiceSpec{{
+			Name:   "web",
+			GitHub: &godo.GitHubSourceSpec{Repo: "foo/baz", Branch: "feature"},
+		}}},
+	}, {
+		name: "image",
+		spec: &godo.AppSpec{Services: []*godo.AppServiceSpec{{
+			Name:  "web",
+			Image: &godo.ImageSourceSpec{RegistryType: godo.ImageSourceSpecRegistryType_DOCR, Repository: "web"},
+		}}},
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			require.Equal(t, test.expected, IsPreviewFor(test.spec, ciCtx))
+		})
+	}
+}
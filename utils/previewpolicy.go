@@ -0,0 +1,169 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/digitalocean/godo"
+	"sigs.k8s.io/yaml"
+)
+
+// PreviewResourcePolicy limits the resources of preview apps to keep their
+// costs down.
+type PreviewResourcePolicy struct {
+	// MaxInstanceCount caps the instance count of all components, including
+	// the bounds of their autoscaling. 0 means no cap.
+	MaxInstanceCount int64 `json:"max_instance_count,omitempty"`
+	// InstanceSizeSlug is the instance size larger components are downgraded
+	// to, for example apps-s-1vcpu-0.5gb. Components that keep autoscaling are
+	// only downgraded if the size supports autoscaling, components running more
+	// than one instance only if the size isn't limited to a single one.
+	InstanceSizeSlug string `json:"instance_size_slug,omitempty"`
+	// DisableAutoscaling replaces autoscaling with a fixed instance count, the
+	// minimum of the autoscaling.
+	DisableAutoscaling bool `json:"disable_autoscaling,omitempty"`
+	// DropComponents are the names of components to remove. Ingress rules
+	// routing to them are removed as well. Unknown names are ignored.
+	DropComponents []string `json:"drop_components,omitempty"`
+}
+
+// ParsePreviewResourcePolicy parses the policy from its YAML representation,
+// for example `{max_instance_count: 1, disable_autoscaling: true}`, or from
+// the YAML file at the given path. An empty string results in no policy.
+func ParsePreviewResourcePolicy(s string) (*PreviewResourcePolicy, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil, nil
+	}
+
+	data := []byte(s)
+	// Not a YAML object, so it must be a path.
+	if !strings.ContainsAny(s, ":{\n") {
+		var err error
+		data, err = os.ReadFile(s)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read preview resource policy: %w", err)
+		}
+	}
+
+	var policy PreviewResourcePolicy
+	if err := yaml.UnmarshalStrict(data, &policy); err != nil {
+		return nil, fmt.Errorf("failed to parse preview resource policy: %w", err)
+	}
+	if policy.MaxInstanceCount < 0 {
+		return nil, errors.New("max_instance_count of the preview resource policy must not be negative")
+	}
+	return &policy, nil
+}
+
+// Apply applies the policy to the given spec. The instance sizes available on
+// App Platform are used to tell which sizes are larger than the policy's one
+// and are required if it sets one.
+func (p *PreviewResourcePolicy) Apply(spec *godo.AppSpec, sizes []*godo.AppInstanceSize) error {
+	var target *instanceSize
+	costs := make(map[string]*instanceSize, len(sizes))
+	for _, size := range sizes {
+		cost, err := strconv.ParseFloat(size.USDPerMonth, 64)
+		if err != nil {
+			// Sizes without a price can't be compared.
+			continue
+		}
+		costs[size.Slug] = &instanceSize{
+			slug:               size.Slug,
+			usdPerMonth:        cost,
+			scalable:           size.Scalable,
+			singleInstanceOnly: size.SingleInstanceOnly,
+		}
+	}
+	if p.InstanceSizeSlug != "" {
+		target = costs[p.InstanceSizeSlug]
+		if target == nil {
+			return fmt.Errorf("unknown instance size %q", p.InstanceSizeSlug)
+		}
+	}
+
+	if len(p.DropComponents) > 0 {
+		p.dropComponents(spec)
+	}
+
+	for _, s := range spec.Services {
+		s.InstanceCount, s.Autoscaling = p.scaling(s.InstanceCount, s.Autoscaling)
+		s.InstanceSizeSlug = target.downgrade(costs[s.InstanceSizeSlug], s.InstanceSizeSlug, s.InstanceCount, s.Autoscaling != nil)
+	}
+	for _, w := range spec.Workers {
+		w.InstanceCount, w.Autoscaling = p.scaling(w.InstanceCount, w.Autoscaling)
+		w.InstanceSizeSlug = target.downgrade(costs[w.InstanceSizeSlug], w.InstanceSizeSlug, w.InstanceCount, w.Autoscaling != nil)
+	}
+	for _, j := range spec.Jobs {
+		j.InstanceCount, _ = p.scaling(j.InstanceCount, nil)
+		j.InstanceSizeSlug = target.downgrade(costs[j.InstanceSizeSlug], j.InstanceSizeSlug, j.InstanceCount, false)
+	}
+	return nil
+}
+
+// dropComponents removes the components to drop and the ingress rules
+// routing to them.
+func (p *PreviewResourcePolicy) dropComponents(spec *godo.AppSpec) {
+	drop := func(name string) bool { return slices.Contains(p.DropComponents, name) }
+	spec.Services = slices.DeleteFunc(spec.Services, func(c *godo.AppServiceSpec) bool { return drop(c.Name) })
+	spec.StaticSites = slices.DeleteFunc(spec.StaticSites, func(c *godo.AppStaticSiteSpec) bool { return drop(c.Name) })
+	spec.Workers = slices.DeleteFunc(spec.Workers, func(c *godo.AppWorkerSpec) bool { return drop(c.Name) })
+	spec.Jobs = slices.DeleteFunc(spec.Jobs, func(c *godo.AppJobSpec) bool { return drop(c.Name) })
+	spec.Functions = slices.DeleteFunc(spec.Functions, func(c *godo.AppFunctionsSpec) bool { return drop(c.Name) })
+	if spec.Ingress != nil {
+		spec.Ingress.Rules = slices.DeleteFunc(spec.Ingress.Rules, func(r *godo.AppIngressSpecRule) bool {
+			return r.Component != nil && drop(r.Component.Name)
+		})
+	}
+}
+
+// instanceSize is an instance size of App Platform.
+type instanceSize struct {
+	slug        string
+	usdPerMonth float64
+	// scalable is whether components of the size can autoscale.
+	scalable bool
+	// singleInstanceOnly is whether components of the size are limited to a
+	// single instance.
+	singleInstanceOnly bool
+}
+
+// downgrade returns the size a component of the given size, instance count
+// and autoscaling gets if t is the size to downgrade to. Sizes that are unset,
+// unknown or not larger than t are kept, as are sizes of components t can't
+// run, i.e. autoscaling ones if t can't autoscale and ones with more than one
+// instance if t is limited to a single one.
+func (t *instanceSize) downgrade(current *instanceSize, slug string, count int64, autoscaling bool) string {
+	if t == nil || current == nil || current.usdPerMonth <= t.usdPerMonth {
+		return slug
+	}
+	if autoscaling && !t.scalable {
+		return slug
+	}
+	if (autoscaling || count > 1) && t.singleInstanceOnly {
+		return slug
+	}
+	return t.slug
+}
+
+// scaling returns the instance count and autoscaling a component with the
+// given ones gets.
+func (p *PreviewResourcePolicy) scaling(count int64, autoscaling *godo.AppAutoscalingSpec) (int64, *godo.AppAutoscalingSpec) {
+	if autoscaling != nil && p.DisableAutoscaling {
+		count, autoscaling = max(autoscaling.MinInstanceCount, 1), nil
+	}
+	if p.MaxInstanceCount == 0 {
+		return count, autoscaling
+	}
+
+	count = min(count, p.MaxInstanceCount)
+	if autoscaling != nil {
+		autoscaling.MinInstanceCount = min(autoscaling.MinInstanceCount, p.MaxInstanceCount)
+		autoscaling.MaxInstanceCount = min(autoscaling.MaxInstanceCount, p.MaxInstanceCount)
+	}
+	return count, autoscaling
+}
@@ -0,0 +1,232 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/digitalocean/godo"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePreviewResourcePolicy(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policy.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("max_instance_count: 2\ndrop_components:\n- worker\n"), 0o600))
+
+	tests := []struct {
+		name     string
+		policy   string
+		expected *PreviewResourcePolicy
+		err      bool
+	}{{
+		name:   "empty",
+		policy: "",
+	}, {
+		name:     "inline",
+		policy:   "{max_instance_count: 1, instance_size_slug: apps-s-1vcpu-0.5gb, disable_autoscaling: true, drop_components: [docs]}",
+		expected: &PreviewResourcePolicy{MaxInstanceCount: 1, InstanceSizeSlug: "apps-s-1vcpu-0.5gb", DisableAutoscaling: true, DropComponents: []string{"docs"}},
+	}, {
+		name:     "file",
+		policy:   path,
+		expected: &PreviewResourcePolicy{MaxInstanceCount: 2, DropComponents: []string{"worker"}},
+	}, {
+		name:   "missing file",
+		policy: filepath.Join(t.TempDir(), "missing.yaml"),
+		err:    true,
+	}, {
+		name:   "unknown field",
+		policy: "{max_instances: 1}",
+		err:    true,
+	}, {
+		name:   "negative instance count",
+		policy: "max_instance_count: -1",
+		err:    true,
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			policy, err := ParsePreviewResourcePolicy(test.policy)
+			if test.err {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, test.expected, policy)
+		})
+	}
+}
+
+func TestPreviewResourcePolicyApply(t *testing.T) {
+	newSpec := func() *godo.AppSpec {
+		return &godo.AppSpec{
+			Name: "foo",
+			Services: []*godo.AppServiceSpec{{
+				Name:             "web",
+				InstanceSizeSlug: "apps-d-2vcpu-4gb",
+				InstanceCount:    3,
+			}, {
+				Name:             "api",
+				InstanceSizeSlug: "apps-d-2vcpu-4gb",
+				Autoscaling:      &godo.AppAutoscalingSpec{MinInstanceCount: 2, MaxInstanceCount: 10},
+			}},
+			StaticSites: []*godo.AppStaticSiteSpec{{Name: "docs"}},
+			Workers: []*godo.AppWorkerSpec{{
+				Name:             "worker",
+				InstanceSizeSlug: "apps-s-1vcpu-0.5gb",
+				InstanceCount:    2,
+			}},
+			Jobs: []*godo.AppJobSpec{{
+				Name:          "migrate",
+				InstanceCount: 2,
+			}},
+			Ingress: &godo.AppIngressSpec{Rules: []*godo.AppIngressSpecRule{{
+				Component: &godo.AppIngressSpecRuleRoutingComponent{Name: "web"},
+			}, {
+				Component: &godo.AppIngressSpecRuleRoutingComponent{Name: "docs"},
+			}}},
+		}
+	}
+
+	sizes := []*godo.AppInstanceSize{
+		{Slug: "apps-s-1vcpu-0.5gb", USDPerMonth: "5.00", SingleInstanceOnly: true},
+		{Slug: "apps-s-1vcpu-1gb", USDPerMonth: "10.00"},
+		{Slug: "apps-d-1vcpu-0.5gb", USDPerMonth: "29.00", Scalable: true},
+		{Slug: "apps-d-2vcpu-4gb", USDPerMonth: "78.00", Scalable: true},
+	}
+
+	tests := []struct {
+		name     string
+		policy   PreviewResourcePolicy
+		expected func(*godo.AppSpec)
+		err      bool
+	}{{
+		name:     "empty",
+		expected: func(*godo.AppSpec) {},
+	}, {
+		name:   "cap instance count",
+		policy: PreviewResourcePolicy{MaxInstanceCount: 1},
+		expected: func(spec *godo.AppSpec) {
+			spec.Services[0].InstanceCount = 1
+			spec.Services[1].Autoscaling = &godo.AppAutoscalingSpec{MinInstanceCount: 1, MaxInstanceCount: 1}
+			spec.Workers[0].InstanceCount = 1
+			spec.Jobs[0].InstanceCount = 1
+		},
+	}, {
+		name:   "instance size",
+		policy: PreviewResourcePolicy{InstanceSizeSlug: "apps-s-1vcpu-1gb"},
+		expected: func(spec *godo.AppSpec) {
+			spec.Services[0].InstanceSizeSlug = "apps-s-1vcpu-1gb"
+			// The autoscaling service keeps its size as shared sizes can't
+			// autoscale, the smaller worker and the job without a size as well.
+		},
+	}, {
+		name:   "scalable instance size",
+		policy: PreviewResourcePolicy{InstanceSizeSlug: "apps-d-1vcpu-0.5gb"},
+		expected: func(spec *godo.AppSpec) {
+			spec.Services[0].InstanceSizeSlug = "apps-d-1vcpu-0.5gb"
+			spec.Services[1].InstanceSizeSlug = "apps-d-1vcpu-0.5gb"
+		},
+	}, {
+		name:   "single instance size",
+		policy: PreviewResourcePolicy{InstanceSizeSlug: "apps-s-1vcpu-0.5gb"},
+		expected: func(*godo.AppSpec) {
+			// The service with 3 instances keeps its size as the size is limited
+			// to a single instance.
+		},
+	}, {
+		name:   "single instance size and cap instance count",
+		policy: PreviewResourcePolicy{InstanceSizeSlug: "apps-s-1vcpu-0.5gb", MaxInstanceCount: 1},
+		expected: func(spec *godo.AppSpec) {
+			spec.Services[0].InstanceCount = 1
+			spec.Services[0].InstanceSizeSlug = "apps-s-1vcpu-0.5gb"
+			spec.Services[1].Autoscaling = &godo.AppAutoscalingSpec{MinInstanceCount: 1, MaxInstanceCount: 1}
+			spec.Workers[0].InstanceCount = 1
+			spec.Jobs[0].InstanceCount = 1
+		},
+	}, {
+		name:   "instance size and disable autoscaling",
+		policy: PreviewResourcePolicy{InstanceSizeSlug: "apps-s-1vcpu-1gb", DisableAutoscaling: true},
+		expected: func(spec *godo.AppSpec) {
+			spec.Services[0].InstanceSizeSlug = "apps-s-1vcpu-1gb"
+			spec.Services[1].InstanceSizeSlug = "apps-s-1vcpu-1gb"
+			spec.Services[1].Autoscaling = nil
+			spec.Services[1].InstanceCount = 2
+		},
+	}, {
+		name:     "unknown instance size",
+		policy:   PreviewResourcePolicy{InstanceSizeSlug: "apps-x-huge"},
+		expected: func(*godo.AppSpec) {},
+		err:      true,
+	}, {
+		name:   "disable autoscaling",
+		policy: PreviewResourcePolicy{DisableAutoscaling: true},
+		expected: func(spec *godo.AppSpec) {
+			spec.Services[1].Autoscaling = nil
+			spec.Services[1].InstanceCount = 2
+		},
+	}, {
+		name:   "disable autoscaling and cap instance count",
+		policy: PreviewResourcePolicy{DisableAutoscaling: true, MaxInstanceCount: 1},
+		expected: func(spec *godo.AppSpec) {
+			spec.Services[0].InstanceCount = 1
+			spec.Services[1].Autoscaling = nil
+			spec.Services[1].InstanceCount = 1
+			spec.Workers[0].InstanceCount = 1
+			spec.Jobs[0].InstanceCount = 1
+		},
+	}, {
+		name:   "drop components",
+		policy: PreviewResourcePolicy{DropComponents: []string{"docs", "worker", "unknown"}},
+		expected: func(spec *godo.AppSpec) {
+			spec.StaticSites = []*godo.AppStaticSiteSpec{}
+			spec.Workers = []*godo.AppWorkerSpec{}
+			spec.Ingress.Rules = spec.Ingress.Rules[:1]
+		},
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			spec := newSpec()
+			err := test.policy.Apply(spec, sizes)
+			if test.err {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+
+			expected := newSpec()
+			test.expected(expected)
+			require.Equal(t, expected, spec)
+		})
+	}
+}
+
+func TestSanitizeSpecForPullRequestPreviewWithResourcePolicy(t *testing.T) {
+	spec := &godo.AppSpec{
+		Name: "foo",
+		Services: []*godo.AppServiceSpec{{
+			Name:          "web",
+			GitHub:        &godo.GitHubSourceSpec{Repo: "foo/bar", Branch: "main", DeployOnPush: true},
+			InstanceCount: 3,
+		}},
+		Workers: []*godo.AppWorkerSpec{{
+			Name:   "worker",
+			GitHub: &godo.GitHubSourceSpec{Repo: "foo/bar", Branch: "main", DeployOnPush: true},
+		}},
+	}
+	ciCtx := &CIContext{Provider: CIProviderGitHub, Repository: "foo/bar", Branch: "feature", PRNumber: 3}
+
+	err := SanitizeSpecForPullRequestPreview(spec, ciCtx, PreviewConfig{
+		Resources: &PreviewResourcePolicy{MaxInstanceCount: 1, DropComponents: []string{"worker"}},
+	})
+	require.NoError(t, err)
+	require.Equal(t, &godo.AppSpec{
+		Name: "pr-3-bar-foo",
+		Services: []*godo.AppServiceSpec{{
+			Name:          "web",
+			GitHub:        &godo.GitHubSourceSpec{Repo: "foo/bar", Branch: "feature"},
+			InstanceCount: 1,
+		}},
+		Workers: []*godo.AppWorkerSpec{},
+	}, spec)
+}
@@ -0,0 +1,181 @@
+package utils
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/digitalocean/godo"
+)
+
+// minRetryBackoff is the time waited before the first retry of a failed call.
+const minRetryBackoff = 500 * time.Millisecond
+
+// RetryConfig configures the retries of failed API calls.
+type RetryConfig struct {
+	// MaxRetries is the maximum amount of retries of a single call.
+	MaxRetries int
+	// MaxBackoff is the maximum time waited between two attempts of a call.
+	MaxBackoff time.Duration
+}
+
+// NewRetryingAppsService returns an AppsService that retries the idempotent
+// calls of the given service on transient errors, i.e. network errors like
+// timeouts or reset connections, rate limiting and server errors. Non-idempotent calls are passed through as is.
+func NewRetryingAppsService(apps godo.AppsService, cfg RetryConfig) godo.AppsService {
+	return &retryingAppsService{AppsService: apps, cfg: cfg}
+}
+
+// retryingAppsService implements the retries of NewRetryingAppsService.
+type retryingAppsService struct {
+	godo.AppsService
+	cfg RetryConfig
+}
+
+// Get implements godo.AppsService.
+func (s *retryingAppsService) Get(ctx context.Context, appID string) (*godo.App, *godo.Response, error) {
+	return retry(ctx, s.cfg, func() (*godo.App, *godo.Response, error) {
+		return s.AppsService.Get(ctx, appID)
+	})
+}
+
+// List implements godo.AppsService.
+func (s *retryingAppsService) List(ctx context.Context, opts *godo.ListOptions) ([]*godo.App, *godo.Response, error) {
+	return retry(ctx, s.cfg, func() ([]*godo.App, *godo.Response, error) {
+		return s.AppsService.List(ctx, opts)
+	})
+}
+
+// Delete implements godo.AppsService.
+func (s *retryingAppsService) Delete(ctx context.Context, appID string) (*godo.Response, error) {
+	_, resp, err := retry(ctx, s.cfg, func() (struct{}, *godo.Response, error) {
+		resp, err := s.AppsService.Delete(ctx, appID)
+		return struct{}{}, resp, err
+	})
+	return resp, err
+}
+
+// Propose implements godo.AppsService.
+func (s *retryingAppsService) Propose(ctx context.Context, propose *godo.AppProposeRequest) (*godo.AppProposeResponse, *godo.Response, error) {
+	return retry(ctx, s.cfg, func() (*godo.AppProposeResponse, *godo.Response, error) {
+		return s.AppsService.Propose(ctx, propose)
+	})
+}
+
+// GetDeployment implements godo.AppsService.
+func (s *retryingAppsService) GetDeployment(ctx context.Context, appID, deploymentID string) (*godo.Deployment, *godo.Response, error) {
+	return retry(ctx, s.cfg, func() (*godo.Deployment, *godo.Response, error) {
+		return s.AppsService.GetDeployment(ctx, appID, deploymentID)
+	})
+}
+
+// ListDeployments implements godo.AppsService.
+func (s *retryingAppsService) ListDeployments(ctx context.Context, appID string, opts *godo.ListOptions) ([]*godo.Deployment, *godo.Response, error) {
+	return retry(ctx, s.cfg, func() ([]*godo.Deployment, *godo.Response, error) {
+		return s.AppsService.ListDeployments(ctx, appID, opts)
+	})
+}
+
+// GetLogs implements godo.AppsService.
+func (s *retryingAppsService) GetLogs(ctx context.Context, appID, deploymentID, component string, logType godo.AppLogType, follow bool, tailLines int) (*godo.AppLogs, *godo.Response, error) {
+	return retry(ctx, s.cfg, func() (*godo.AppLogs, *godo.Response, error) {
+		return s.AppsService.GetLogs(ctx, appID, deploymentID, component, logType, follow, tailLines)
+	})
+}
+
+// ListInstanceSizes implements godo.AppsService.
+func (s *retryingAppsService) ListInstanceSizes(ctx context.Context) ([]*godo.AppInstanceSize, *godo.Response, error) {
+	return retry(ctx, s.cfg, func() ([]*godo.AppInstanceSize, *godo.Response, error) {
+		return s.AppsService.ListInstanceSizes(ctx)
+	})
+}
+
+// retry calls fn until it succeeds, fails with a non-transient error or the
+// retries are exhausted.
+func retry[T any](ctx context.Context, cfg RetryConfig, fn func() (T, *godo.Response, error)) (T, *godo.Response, error) {
+	for attempt := 0; ; attempt++ {
+		v, resp, err := fn()
+		if err == nil || attempt >= cfg.MaxRetries || !isTransient(ctx, resp, err) {
+			return v, resp, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return v, resp, err
+		case <-time.After(retryBackoff(attempt, resp, cfg.MaxBackoff)):
+		}
+	}
+}
+
+// isTransient returns whether or not a call that failed with the given
+// response and error is worth retrying.
+func isTransient(ctx context.Context, resp *godo.Response, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	if resp == nil || resp.Response == nil {
+		// The request didn't get a response at all.
+		return isTransientNetworkError(err)
+	}
+	return resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= http.StatusInternalServerError
+}
+
+// isTransientNetworkError returns whether the error of a request that didn't
+// get a response is a network error worth retrying. Errors that fail the same
+// way on every attempt, like invalid certificates or malformed requests, are not.
+func isTransientNetworkError(err error) bool {
+	var (
+		certErr      *tls.CertificateVerificationError
+		authorityErr x509.UnknownAuthorityError
+		hostnameErr  x509.HostnameError
+		invalidErr   x509.CertificateInvalidError
+		recordErr    tls.RecordHeaderError
+	)
+	if errors.As(err, &certErr) || errors.As(err, &authorityErr) || errors.As(err, &hostnameErr) ||
+		errors.As(err, &invalidErr) || errors.As(err, &recordErr) {
+		return false
+	}
+
+	// Every error of the HTTP client is a net.Error, so only those of the
+	// connection itself and timeouts are considered transient.
+	var opErr *net.OpError
+	var netErr net.Error
+	return errors.As(err, &opErr) ||
+		(errors.As(err, &netErr) && netErr.Timeout()) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, io.EOF) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.EPIPE)
+}
+
+// retryBackoff returns the time to wait before the given retry attempt.
+// Rate limited calls wait until the rate limit resets. All others back off
+// exponentially with jitter. Either way, the wait is capped by maxBackoff.
+func retryBackoff(attempt int, resp *godo.Response, maxBackoff time.Duration) time.Duration {
+	var backoff time.Duration
+	if resp != nil && resp.Response != nil && resp.StatusCode == http.StatusTooManyRequests {
+		if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+			backoff = time.Duration(seconds) * time.Second
+		} else if !resp.Rate.Reset.IsZero() {
+			backoff = time.Until(resp.Rate.Reset.Time)
+		}
+	}
+	if backoff <= 0 {
+		backoff = minRetryBackoff << min(attempt, 16)
+		// Jitter avoids synchronized retries of concurrent runs.
+		backoff = backoff/2 + rand.N(backoff/2)
+	}
+	if maxBackoff > 0 && backoff > maxBackoff {
+		backoff = maxBackoff
+	}
+	return backoff
+}
@@ -0,0 +1,133 @@
+package utils
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/digitalocean/godo"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRetryingAppsService(t *testing.T) {
+	ctx := context.Background()
+	app := &godo.App{ID: "app-id"}
+	cfg := RetryConfig{MaxRetries: 2, MaxBackoff: time.Millisecond}
+	connectionReset := &url.Error{Op: "Get", URL: "https://api.digitalocean.com", Err: &net.OpError{Op: "read", Net: "tcp", Err: syscall.ECONNRESET}}
+	unknownAuthority := &url.Error{Op: "Get", URL: "https://api.digitalocean.com", Err: &tls.CertificateVerificationError{Err: x509.UnknownAuthorityError{}}}
+
+	tests := []struct {
+		name        string
+		appsService func() *mockedAppsService
+		err         bool
+	}{{
+		name: "success",
+		appsService: func() *mockedAppsService {
+			as := &mockedAppsService{}
+			as.On("Get", ctx, "app-id").Return(app, &godo.Response{}, nil).Once()
+			return as
+		},
+	}, {
+		name: "retries transient errors",
+		appsService: func() *mockedAppsService {
+			as := &mockedAppsService{}
+			as.On("Get", ctx, "app-id").Return((*godo.App)(nil), (*godo.Response)(nil), connectionReset).Once()
+			as.On("Get", ctx, "app-id").Return((*godo.App)(nil), responseWithStatus(http.StatusTooManyRequests), errors.New("rate limited")).Once()
+			as.On("Get", ctx, "app-id").Return(app, &godo.Response{}, nil).Once()
+			return as
+		},
+	}, {
+		name: "gives up after max retries",
+		appsService: func() *mockedAppsService {
+			as := &mockedAppsService{}
+			as.On("Get", ctx, "app-id").Return((*godo.App)(nil), responseWithStatus(http.StatusBadGateway), errors.New("bad gateway")).Times(3)
+			return as
+		},
+		err: true,
+	}, {
+		name: "doesn't retry certificate errors",
+		appsService: func() *mockedAppsService {
+			as := &mockedAppsService{}
+			as.On("Get", ctx, "app-id").Return((*godo.App)(nil), (*godo.Response)(nil), unknownAuthority).Once()
+			return as
+		},
+		err: true,
+	}, {
+		name: "doesn't retry client errors",
+		appsService: func() *mockedAppsService {
+			as := &mockedAppsService{}
+			as.On("Get", ctx, "app-id").Return((*godo.App)(nil), responseWithStatus(http.StatusNotFound), errors.New("not found")).Once()
+			return as
+		},
+		err: true,
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			as := test.appsService()
+			got, _, err := NewRetryingAppsService(as, cfg).Get(ctx, "app-id")
+			if test.err {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+				require.Equal(t, app, got)
+			}
+			as.AssertExpectations(t)
+		})
+	}
+}
+
+func TestRetryingAppsServiceListInstanceSizes(t *testing.T) {
+	ctx := context.Background()
+	sizes := []*godo.AppInstanceSize{{Slug: "apps-s-1vcpu-0.5gb"}}
+
+	as := &mockedAppsService{}
+	as.On("ListInstanceSizes", ctx).Return(([]*godo.AppInstanceSize)(nil), responseWithStatus(http.StatusServiceUnavailable), errors.New("unavailable")).Once()
+	as.On("ListInstanceSizes", ctx).Return(sizes, &godo.Response{}, nil).Once()
+
+	got, _, err := NewRetryingAppsService(as, RetryConfig{MaxRetries: 2, MaxBackoff: time.Millisecond}).ListInstanceSizes(ctx)
+	require.NoError(t, err)
+	require.Equal(t, sizes, got)
+	as.AssertExpectations(t)
+}
+
+func TestIsTransientNetworkError(t *testing.T) {
+	timeout := &url.Error{Op: "Get", URL: "https://api.digitalocean.com", Err: &net.DNSError{IsTimeout: true}}
+	hostname := &url.Error{Op: "Get", URL: "https://api.digitalocean.com", Err: x509.HostnameError{Host: "api.digitalocean.com", Certificate: &x509.Certificate{}}}
+
+	require.True(t, isTransientNetworkError(timeout))
+	require.True(t, isTransientNetworkError(&url.Error{Op: "Get", URL: "https://api.digitalocean.com", Err: io.ErrUnexpectedEOF}))
+	require.True(t, isTransientNetworkError(&net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED}))
+	require.False(t, isTransientNetworkError(hostname))
+	require.False(t, isTransientNetworkError(&url.Error{Op: "parse", URL: "://", Err: errors.New("missing protocol scheme")}))
+}
+
+func TestRetryBackoff(t *testing.T) {
+	rateLimited := responseWithStatus(http.StatusTooManyRequests)
+	rateLimited.Header.Set("Retry-After", "3")
+	require.Equal(t, 3*time.Second, retryBackoff(0, rateLimited, time.Minute))
+	require.Equal(t, time.Second, retryBackoff(0, rateLimited, time.Second))
+
+	rateLimited = responseWithStatus(http.StatusTooManyRequests)
+	rateLimited.Rate.Reset = godo.Timestamp{Time: time.Now().Add(time.Hour)}
+	require.Equal(t, time.Minute, retryBackoff(0, rateLimited, time.Minute))
+
+	for attempt := 0; attempt < 5; attempt++ {
+		backoff := retryBackoff(attempt, nil, time.Hour)
+		require.GreaterOrEqual(t, backoff, minRetryBackoff<<attempt/2)
+		require.Less(t, backoff, minRetryBackoff<<attempt)
+	}
+	require.Equal(t, time.Second, retryBackoff(10, nil, time.Second))
+}
+
+func responseWithStatus(status int) *godo.Response {
+	return &godo.Response{Response: &http.Response{StatusCode: status, Header: http.Header{}}}
+}
@@ -0,0 +1,70 @@
+package utils
+
+import (
+	"fmt"
+	"os"
+
+	gha "github.com/sethvargo/go-githubactions"
+)
+
+// Runtime is the environment the actions run in. It provides the inputs,
+// takes the outputs and logs of the action and describes the context it runs
+// in. It's implemented by *gha.Action for GitHub Actions and by CLIRuntime
+// for running the actions as a standalone CLI.
+type Runtime interface {
+	GetInput(name string) string
+	SetOutput(name, value string)
+	AddMask(value string)
+	AddStepSummary(markdown string)
+	Group(title string)
+	EndGroup()
+	Infof(format string, args ...any)
+	Warningf(format string, args ...any)
+	Errorf(format string, args ...any)
+	Fatalf(format string, args ...any)
+	Context() (*gha.GitHubContext, error)
+}
+
+var _ Runtime = (*gha.Action)(nil)
+
+// runtimeEnv is the environment variable selecting the runtime explicitly,
+// either cli or github.
+const runtimeEnv = cliEnvPrefix + "RUNTIME"
+
+// NewRuntime returns the runtime the action currently runs in. Within GitHub
+// Actions, that's the action itself. Everywhere else, it's a CLI reading the
+// inputs defined in the given action metadata (action.yml) from the command
+// line arguments and environment.
+// The returned function must be called once the action is done.
+func NewRuntime(actionYAML []byte) (Runtime, func(), error) {
+	cli, err := useCLIRuntime(os.Args[1:], os.Getenv)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !cli {
+		return gha.New(), func() {}, nil
+	}
+
+	r, err := NewCLIRuntime(actionYAML, os.Args[1:], os.Getenv, os.Stdout, os.Stderr)
+	if err != nil {
+		return nil, nil, err
+	}
+	return r, r.PrintOutputs, nil
+}
+
+// useCLIRuntime returns whether the action runs as a CLI. Unless selected
+// explicitly via APP_ACTION_RUNTIME, that's the case outside of GitHub Actions
+// and if there are command line arguments, as the action itself never gets
+// any. The latter is the case for the CLI run by a step of a workflow.
+func useCLIRuntime(args []string, getenv func(string) string) (bool, error) {
+	switch r := getenv(runtimeEnv); r {
+	case "cli":
+		return true, nil
+	case "github":
+		return false, nil
+	case "":
+		return getenv("GITHUB_ACTIONS") != "true" || len(args) > 0, nil
+	default:
+		return false, fmt.Errorf("invalid %s %q, must be cli or github", runtimeEnv, r)
+	}
+}
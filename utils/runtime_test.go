@@ -0,0 +1,52 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestUseCLIRuntime(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		env      map[string]string
+		expected bool
+		err      bool
+	}{{
+		name:     "outside of GitHub Actions",
+		expected: true,
+	}, {
+		name: "GitHub Actions",
+		env:  map[string]string{"GITHUB_ACTIONS": "true"},
+	}, {
+		name:     "GitHub Actions with flags",
+		args:     []string{"--app-name", "foo"},
+		env:      map[string]string{"GITHUB_ACTIONS": "true"},
+		expected: true,
+	}, {
+		name:     "GitHub Actions with CLI runtime",
+		env:      map[string]string{"GITHUB_ACTIONS": "true", runtimeEnv: "cli"},
+		expected: true,
+	}, {
+		name: "GitHub runtime with flags",
+		args: []string{"--app-name", "foo"},
+		env:  map[string]string{runtimeEnv: "github"},
+	}, {
+		name: "invalid runtime",
+		env:  map[string]string{runtimeEnv: "gitlab"},
+		err:  true,
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cli, err := useCLIRuntime(test.args, func(k string) string { return test.env[k] })
+			if test.err {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, test.expected, cli)
+		})
+	}
+}
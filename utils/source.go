@@ -0,0 +1,187 @@
+package utils
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/digitalocean/godo"
+)
+
+// Hosts of the repositories of the VCS specific source types.
+const (
+	githubHost    = "github.com"
+	gitlabHost    = "gitlab.com"
+	bitbucketHost = "bitbucket.org"
+)
+
+// repoURL is a repository's clone URL or path split into its parts.
+type repoURL struct {
+	// prefix is everything before the path, like the scheme and the host.
+	prefix string
+	// host is the host of the repository or empty if unknown.
+	host string
+	// path is the path of the repository, like owner/name.
+	path string
+	// suffix is everything after the path, like a .git suffix.
+	suffix string
+}
+
+// parseRepoURL parses a repository's clone URL, for example
+// https://github.com/owner/name.git or git@github.com:owner/name.git, or its
+// path, for example owner/name.
+func parseRepoURL(s string) repoURL {
+	var u repoURL
+	rest := strings.TrimSpace(s)
+	if i := strings.Index(rest, "://"); i >= 0 {
+		authority, path, _ := strings.Cut(rest[i+3:], "/")
+		u.prefix = rest[:i+3] + authority + "/"
+		u.host = hostname(authority)
+		rest = path
+	} else if i := strings.Index(rest, ":"); i >= 0 && !strings.Contains(rest[:i], "/") {
+		// The scp-like syntax of SSH URLs.
+		u.prefix = rest[:i+1]
+		u.host = hostname(rest[:i])
+		rest = rest[i+1:]
+	}
+
+	path := strings.TrimSuffix(rest, "/")
+	path = strings.TrimSuffix(path, ".git")
+	u.path = path
+	u.suffix = rest[len(path):]
+	return u
+}
+
+// hostname returns the lowercased host of the given authority, without any
+// user info or port.
+func hostname(authority string) string {
+	if _, host, ok := strings.Cut(authority, "@"); ok {
+		authority = host
+	}
+	if u, err := url.Parse("//" + authority); err == nil {
+		authority = u.Hostname()
+	}
+	return strings.ToLower(authority)
+}
+
+// withPath returns the URL with the path replaced by the given one.
+func (u repoURL) withPath(path string) string {
+	return u.prefix + path + u.suffix
+}
+
+// matches returns whether both URLs point to the same repository. Hosts are
+// only compared if both are known.
+func (u repoURL) matches(other repoURL) bool {
+	if u.host != "" && other.host != "" && u.host != other.host {
+		return false
+	}
+	return strings.EqualFold(strings.Trim(u.path, "/"), strings.Trim(other.path, "/"))
+}
+
+// VCSSourceType is the type of a component's source in a repository.
+type VCSSourceType string
+
+const (
+	// VCSSourceTypeGitHub is a GitHub repository.
+	VCSSourceTypeGitHub VCSSourceType = "GitHub"
+	// VCSSourceTypeGitLab is a GitLab repository.
+	VCSSourceTypeGitLab VCSSourceType = "GitLab"
+	// VCSSourceTypeBitbucket is a Bitbucket repository.
+	VCSSourceTypeBitbucket VCSSourceType = "Bitbucket"
+	// VCSSourceTypeGit is any Git repository, referenced by its clone URL.
+	VCSSourceTypeGit VCSSourceType = "Git"
+)
+
+// VCSSource is the source of a component built from a repository.
+type VCSSource struct {
+	// Type is the type of the source.
+	Type VCSSourceType
+	// Repo is the repository as referenced by the spec, either its path like
+	// owner/name or, for Git sources, its clone URL.
+	Repo string
+	// Branch is the branch the component is built from.
+	Branch string
+	// DeployOnPush is whether the component is deployed on pushes to the
+	// branch. It's always false for Git sources.
+	DeployOnPush bool
+
+	// repo is the parsed repository.
+	repo repoURL
+	// set makes the component build the branch of the repository with the
+	// given path. Deploy on push is disabled.
+	set func(path, branch string)
+}
+
+// VCSSourceOf returns the source of the component if it's built from a
+// repository, or nil otherwise.
+func VCSSourceOf(c godo.AppBuildableComponentSpec) *VCSSource {
+	if ref := c.GetGitHub(); ref != nil {
+		return &VCSSource{
+			Type:         VCSSourceTypeGitHub,
+			Repo:         ref.Repo,
+			Branch:       ref.Branch,
+			DeployOnPush: ref.DeployOnPush,
+			repo:         repoURL{host: githubHost, path: ref.Repo},
+			set: func(path, branch string) {
+				ref.Repo, ref.Branch, ref.DeployOnPush = path, branch, false
+			},
+		}
+	}
+	if ref := c.GetGitLab(); ref != nil {
+		return &VCSSource{
+			Type:         VCSSourceTypeGitLab,
+			Repo:         ref.Repo,
+			Branch:       ref.Branch,
+			DeployOnPush: ref.DeployOnPush,
+			repo:         repoURL{host: gitlabHost, path: ref.Repo},
+			set: func(path, branch string) {
+				ref.Repo, ref.Branch, ref.DeployOnPush = path, branch, false
+			},
+		}
+	}
+	if ref := c.GetBitbucket(); ref != nil {
+		return &VCSSource{
+			Type:         VCSSourceTypeBitbucket,
+			Repo:         ref.Repo,
+			Branch:       ref.Branch,
+			DeployOnPush: ref.DeployOnPush,
+			repo:         repoURL{host: bitbucketHost, path: ref.Repo},
+			set: func(path, branch string) {
+				ref.Repo, ref.Branch, ref.DeployOnPush = path, branch, false
+			},
+		}
+	}
+	if ref := c.GetGit(); ref != nil {
+		repo := parseRepoURL(ref.RepoCloneURL)
+		return &VCSSource{
+			Type:   VCSSourceTypeGit,
+			Repo:   ref.RepoCloneURL,
+			Branch: ref.Branch,
+			repo:   repo,
+			set: func(path, branch string) {
+				ref.RepoCloneURL, ref.Branch = repo.withPath(path), branch
+			},
+		}
+	}
+	return nil
+}
+
+// isRepository returns whether the source is the repository of the context.
+func (c *CIContext) isRepository(src *VCSSource) bool {
+	return src.repo.matches(c.repoURL(c.Repository))
+}
+
+// IsSourceRepository returns whether the source is the repository the
+// context's branch lives in.
+func (c *CIContext) IsSourceRepository(src *VCSSource) bool {
+	return src.repo.matches(c.repoURL(c.SourceRepository()))
+}
+
+// repoURL returns the URL of the repository with the given path on the
+// context's server.
+func (c *CIContext) repoURL(path string) repoURL {
+	u := parseRepoURL(path)
+	if c.ServerURL != "" {
+		u.host = parseRepoURL(c.ServerURL + "/").host
+	}
+	return u
+}